@@ -0,0 +1,237 @@
+// Package certs generates a local certificate authority and the certificates
+// of the host names clients ask for, so that mocks can be served over HTTPS
+// without providing certificates.
+package certs
+
+import (
+	"container/list"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// caValidity is how long the generated CA is valid.
+	caValidity = 10 * 365 * 24 * time.Hour
+	// leafValidity is how long the certificates of the host names are valid,
+	// within the 398 days some clients accept.
+	leafValidity = 365 * 24 * time.Hour
+	// maxLeaves is how many certificates of host names are kept. Clients can
+	// ask for any host name, so the least recently used ones are dropped past
+	// it and issued again if asked for.
+	maxLeaves = 1000
+	// CertFile and KeyFile are the files the CA is kept in by LoadOrCreateCA.
+	CertFile = "ca.pem"
+	KeyFile  = "ca-key.pem"
+)
+
+// CA is a certificate authority that issues the certificates of host names on
+// demand.
+type CA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	pem  []byte
+
+	mu sync.Mutex
+	// leaves holds the issued certificates by host name, in recently used
+	// order in lru.
+	leaves map[string]*list.Element
+	lru    *list.List
+}
+
+type leaf struct {
+	host string
+	cert *tls.Certificate
+}
+
+// NewCA generates a certificate authority that lives in memory.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "echo local CA", Organization: []string{"echo"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return newCA(der, key)
+}
+
+// LoadOrCreateCA loads the certificate authority kept in dir, or generates
+// one and keeps it there, so that it only has to be trusted once.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certPath, keyPath := filepath.Join(dir, CertFile), filepath.Join(dir, KeyFile)
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		return parseCA(certPEM, keyPEM)
+	}
+	// Only a CA that's missing altogether is generated, never half of one.
+	if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to load the CA: %v", errors.Join(certErr, keyErr))
+	}
+
+	ca, err := NewCA()
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(ca.key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(certPath, ca.pem, 0o644); err != nil { //nolint:gosec // the certificate is public
+		return nil, err
+	}
+	return ca, nil
+}
+
+func parseCA(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("the CA certificate and key must be PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("invalid CA key: it can't sign certificates")
+	}
+	return newCA(certBlock.Bytes, signer)
+}
+
+func newCA(der []byte, key crypto.Signer) (*CA, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	if !cert.IsCA {
+		return nil, errors.New("invalid CA certificate: it's not a CA")
+	}
+	return &CA{
+		cert:   cert,
+		key:    key,
+		pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		leaves: map[string]*list.Element{},
+		lru:    list.New(),
+	}, nil
+}
+
+// PEM returns the certificate of the CA, to be added to trust stores.
+func (ca *CA) PEM() []byte {
+	return ca.pem
+}
+
+// GetCertificate returns the certificate of the host name the client asks for
+// with SNI, issuing it on the first request. Clients that don't send a host
+// name, like those connecting to an IP address, get a certificate for the
+// address they connected to. Only the most recently used certificates are
+// kept. It's meant for tls.Config.GetCertificate.
+func (ca *CA) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := hello.ServerName
+	if host == "" {
+		host = "localhost"
+		if hello.Conn != nil {
+			if addr, ok := hello.Conn.LocalAddr().(*net.TCPAddr); ok {
+				host = addr.IP.String()
+			}
+		}
+	}
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if el, ok := ca.leaves[host]; ok {
+		if cert := el.Value.(*leaf).cert; time.Now().Before(cert.Leaf.NotAfter) {
+			ca.lru.MoveToFront(el)
+			return cert, nil
+		}
+		ca.lru.Remove(el)
+		delete(ca.leaves, host)
+	}
+	cert, err := ca.issue(host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to issue a certificate for %s: %v", host, err)
+	}
+	ca.leaves[host] = ca.lru.PushFront(&leaf{host: host, cert: cert})
+	if ca.lru.Len() > maxLeaves {
+		oldest := ca.lru.Remove(ca.lru.Back()).(*leaf)
+		delete(ca.leaves, oldest.host)
+	}
+	return cert, nil
+}
+
+// issue returns a certificate for the host name or IP address signed by the
+// CA.
+func (ca *CA) issue(host string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host, Organization: []string{"echo"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// serialNumber returns a random serial number of 128 bits.
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
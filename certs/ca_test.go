@@ -0,0 +1,110 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCertificate(t *testing.T) {
+	ca, err := NewCA()
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(ca.PEM()))
+
+	tests := []struct {
+		name  string
+		hello *tls.ClientHelloInfo
+		host  string
+	}{
+		{
+			name:  "host name",
+			hello: &tls.ClientHelloInfo{ServerName: "api.example.com"},
+			host:  "api.example.com",
+		},
+		{
+			name:  "no host name",
+			hello: &tls.ClientHelloInfo{},
+			host:  "localhost",
+		},
+		{
+			name:  "IP address",
+			hello: &tls.ClientHelloInfo{Conn: &conn{local: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}}},
+			host:  "127.0.0.1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert, err := ca.GetCertificate(test.hello)
+			assert.NoError(t, err)
+			_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: test.host, Roots: roots})
+			assert.NoError(t, err)
+
+			again, err := ca.GetCertificate(test.hello)
+			assert.NoError(t, err)
+			assert.Same(t, cert, again, "certificates are issued once")
+		})
+	}
+}
+
+func TestGetCertificateEvictsLeastRecentlyUsed(t *testing.T) {
+	ca, err := NewCA()
+	assert.NoError(t, err)
+	first, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "first.example.com"})
+	assert.NoError(t, err)
+	second, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "second.example.com"})
+	assert.NoError(t, err)
+	for i := range maxLeaves - 1 {
+		_, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: fmt.Sprintf("host-%d.example.com", i)})
+		assert.NoError(t, err)
+		if i == 0 {
+			again, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "first.example.com"})
+			assert.NoError(t, err)
+			assert.Same(t, first, again)
+		}
+	}
+	assert.Len(t, ca.leaves, maxLeaves)
+
+	again, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "first.example.com"})
+	assert.NoError(t, err)
+	assert.Same(t, first, again, "recently used certificates are kept")
+	again, err = ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "second.example.com"})
+	assert.NoError(t, err)
+	assert.NotSame(t, second, again, "the least recently used certificate is issued again")
+}
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+	created, err := LoadOrCreateCA(dir)
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, KeyFile))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := LoadOrCreateCA(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, created.PEM(), loaded.PEM())
+	_, err = loaded.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, CertFile)))
+	_, err = LoadOrCreateCA(dir)
+	assert.Error(t, err, "a key without its certificate isn't replaced")
+}
+
+// conn is a connection that only has a local address.
+type conn struct {
+	net.Conn
+	local net.Addr
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.local
+}
@@ -0,0 +1,475 @@
+// Package config reads the configuration of the echo server from command
+// line flags, ECHO_* environment variables and a YAML or TOML file, in that
+// order of precedence, over the defaults.
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/certs"
+	"github.com/Alvaroalonsobabbel/echo/server"
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of the environment variables. The variable of each
+// flag is its name in upper case with dashes turned into underscores:
+// -seed-file is ECHO_SEED_FILE.
+const envPrefix = "ECHO_"
+
+// Config is the configuration of the server.
+type Config struct {
+	// Addr is the address the server listens on.
+	Addr string `yaml:"addr" validate:"required,hostname_port"`
+	// Storage is the storage backend: sqlite or memory.
+	Storage string `yaml:"storage" validate:"oneof=sqlite memory"`
+	// DB is the SQLite database file or DSN. An in-memory database is used
+	// when it's empty.
+	DB string `yaml:"db" validate:"excluded_if=Storage memory"`
+	// Seed loads the seed endpoints on start. By default only in-memory
+	// databases are seeded.
+	Seed *bool `yaml:"seed"`
+	// SeedFile is a bundle, as exported by GET /endpoints/export, seeded
+	// instead of the built-in endpoints.
+	SeedFile string `yaml:"seedFile" validate:"omitempty,file"`
+	// LogLevel is the minimum level of the logs: debug, info, warn or error.
+	LogLevel string `yaml:"logLevel" validate:"oneof=debug info warn error"`
+	// Fixtures is the directory the files served as response bodies are read
+	// from.
+	Fixtures string `yaml:"fixtures" validate:"omitempty,dir"`
+	// Mocks is the directory of YAML and JSON files defining endpoints, which
+	// are loaded on start and reloaded when they change.
+	Mocks string `yaml:"mocks" validate:"omitempty,dir"`
+	// MocksInterval is how often the mocks directory is checked for changes.
+	MocksInterval time.Duration `yaml:"mocksInterval" validate:"gt=0"`
+	API           API           `yaml:"api"`
+	Proxy         Proxy         `yaml:"proxy"`
+	Auth          Auth          `yaml:"auth"`
+	TLS           TLS           `yaml:"tls"`
+	// HTTP2 serves HTTP/2 over TLS and as cleartext h2c on plain connections,
+	// besides HTTP/1.1.
+	HTTP2    bool     `yaml:"http2"`
+	Timeouts Timeouts `yaml:"timeouts"`
+}
+
+// API sets where the API is served.
+type API struct {
+	// Prefix is the path prefix the API is served under, like /__echo.
+	Prefix string `yaml:"prefix"`
+	// Addr is the address the API listens on apart from the mock endpoints.
+	Addr string `yaml:"addr" validate:"omitempty,hostname_port"`
+}
+
+// Proxy is the initial proxy configuration.
+type Proxy struct {
+	Mode          string   `yaml:"mode"`
+	Upstream      string   `yaml:"upstream"`
+	RecordQuery   bool     `yaml:"recordQuery"`
+	RecordHeaders []string `yaml:"recordHeaders"`
+	RecordBody    bool     `yaml:"recordBody"`
+}
+
+// Auth lists the credentials of the API. API keys are written as
+// key[:role[:namespace+namespace...]] and users as
+// user:password[:role[:namespace+namespace...]]. Credentials without a role
+// are admins.
+type Auth struct {
+	APIKeys   []string `yaml:"apiKeys"`
+	Users     []string `yaml:"users"`
+	JWTSecret string   `yaml:"jwtSecret"`
+	JWTScope  string   `yaml:"jwtScope"`
+}
+
+// TLS serves the mock endpoints and the API over HTTPS, with the given
+// certificate or with certificates issued on the fly by a local CA. Client
+// certificates are verified against ClientCA, as set by ClientAuth: request
+// verifies them when they are presented and require rejects clients without
+// one.
+type TLS struct {
+	Cert string `yaml:"cert" validate:"required_with=Key,omitempty,file"`
+	Key  string `yaml:"key" validate:"required_with=Cert,omitempty,file"`
+	// Auto generates a local CA and a certificate for every host name clients
+	// ask for.
+	Auto bool `yaml:"auto" validate:"excluded_with=Cert"`
+	// CADir keeps the generated CA across restarts, so it's trusted once. The
+	// CA only lives in memory when it's empty.
+	CADir      string `yaml:"caDir" validate:"excluded_without=Auto"`
+	ClientCA   string `yaml:"clientCA" validate:"required_with=ClientAuth,omitempty,file"`
+	ClientAuth string `yaml:"clientAuth" validate:"required_with=ClientCA,omitempty,oneof=request require"`
+}
+
+// Timeouts of the server connections and of the requests forwarded to the
+// upstream. Zero means no timeout. Shutdown is how long the requests in flight
+// have to finish when the server stops.
+type Timeouts struct {
+	Read       time.Duration `yaml:"read" validate:"gte=0"`
+	ReadHeader time.Duration `yaml:"readHeader" validate:"gte=0"`
+	Write      time.Duration `yaml:"write" validate:"gte=0"`
+	Idle       time.Duration `yaml:"idle" validate:"gte=0"`
+	Proxy      time.Duration `yaml:"proxy" validate:"gte=0"`
+	Shutdown   time.Duration `yaml:"shutdown" validate:"gte=0"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
+		Addr:          ":3000",
+		Storage:       "sqlite",
+		LogLevel:      "info",
+		MocksInterval: time.Second,
+		HTTP2:         true,
+		Proxy:         Proxy{Mode: string(server.Playback)},
+		Timeouts: Timeouts{
+			Read:       30 * time.Second,
+			ReadHeader: 10 * time.Second,
+			Write:      60 * time.Second,
+			Idle:       2 * time.Minute,
+			Proxy:      server.DefaultProxyTimeout,
+			Shutdown:   10 * time.Second,
+		},
+	}
+}
+
+// setting is a configuration value that can be set with a flag and an
+// environment variable.
+type setting struct {
+	name   string
+	usage  string
+	isBool bool
+	set    func(c *Config, v string) error
+}
+
+func (s *setting) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+var settings = []*setting{
+	{name: "addr", usage: "Address the server listens on", set: str(func(c *Config) *string { return &c.Addr })},
+	{name: "storage", usage: "Storage backend: sqlite or memory", set: str(func(c *Config) *string { return &c.Storage })},
+	{name: "db", usage: "SQLite database file or DSN. Uses an in-memory database when empty", set: str(func(c *Config) *string { return &c.DB })},
+	{name: "seed", usage: "Load the seed endpoints on start. Defaults to true for in-memory databases", isBool: true, set: func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.Seed = &b
+		return err
+	}},
+	{name: "seed-file", usage: "Bundle of endpoints seeded instead of the built-in ones", set: str(func(c *Config) *string { return &c.SeedFile })},
+	{name: "log-level", usage: "Minimum level of the logs: debug, info, warn or error", set: str(func(c *Config) *string { return &c.LogLevel })},
+	{name: "fixtures", usage: "Directory the files served as response bodies are read from", set: str(func(c *Config) *string { return &c.Fixtures })},
+	{name: "mocks", usage: "Directory of YAML and JSON files defining endpoints, reloaded when they change", set: str(func(c *Config) *string { return &c.Mocks })},
+	{name: "mocks-interval", usage: "How often the mocks directory is checked for changes", set: duration(func(c *Config) *time.Duration { return &c.MocksInterval })},
+	{name: "api-prefix", usage: "Path prefix the API is served under, like /__echo. The API is served at the root when empty", set: str(func(c *Config) *string { return &c.API.Prefix })},
+	{name: "api-addr", usage: "Address the API listens on, like :3001, apart from the mock endpoints", set: str(func(c *Config) *string { return &c.API.Addr })},
+	{name: "proxy-mode", usage: "What to do with unmatched requests: playback, record or passthrough", set: str(func(c *Config) *string { return &c.Proxy.Mode })},
+	{name: "upstream", usage: "Upstream URL unmatched requests are forwarded to in record and passthrough modes", set: str(func(c *Config) *string { return &c.Proxy.Upstream })},
+	{name: "record-query", usage: "Match recorded endpoints on the query params", isBool: true, set: boolean(func(c *Config) *bool { return &c.Proxy.RecordQuery })},
+	{name: "record-headers", usage: "Comma separated headers recorded endpoints are matched on", set: list(func(c *Config) *[]string { return &c.Proxy.RecordHeaders })},
+	{name: "record-body", usage: "Match recorded endpoints on the request body", isBool: true, set: boolean(func(c *Config) *bool { return &c.Proxy.RecordBody })},
+	{name: "api-keys", usage: "Comma separated key[:role[:namespace+namespace...]] API keys accepted by the API", set: list(func(c *Config) *[]string { return &c.Auth.APIKeys })},
+	{name: "basic-auth", usage: "Comma separated user:password[:role[:namespace+namespace...]] users accepted by the API", set: list(func(c *Config) *[]string { return &c.Auth.Users })},
+	{name: "jwt-secret", usage: "HMAC secret of the Bearer tokens accepted by the API", set: str(func(c *Config) *string { return &c.Auth.JWTSecret })},
+	{name: "jwt-scope", usage: "Scope Bearer tokens must grant to use the API", set: str(func(c *Config) *string { return &c.Auth.JWTScope })},
+	{name: "tls-cert", usage: "Certificate file to serve HTTPS with", set: str(func(c *Config) *string { return &c.TLS.Cert })},
+	{name: "tls-key", usage: "Key file of the certificate to serve HTTPS with", set: str(func(c *Config) *string { return &c.TLS.Key })},
+	{name: "tls-auto", usage: "Serve HTTPS with certificates issued by a generated local CA", isBool: true, set: boolean(func(c *Config) *bool { return &c.TLS.Auto })},
+	{name: "tls-ca-dir", usage: "Directory the generated CA is kept in across restarts", set: str(func(c *Config) *string { return &c.TLS.CADir })},
+	{name: "tls-client-ca", usage: "File of the CA certificates client certificates are verified against", set: str(func(c *Config) *string { return &c.TLS.ClientCA })},
+	{name: "tls-client-auth", usage: "Client certificates to ask for: request or require", set: str(func(c *Config) *string { return &c.TLS.ClientAuth })},
+	{name: "http2", usage: "Serve HTTP/2 over TLS and h2c on plain connections", isBool: true, set: boolean(func(c *Config) *bool { return &c.HTTP2 })},
+	{name: "read-timeout", usage: "Maximum duration of reading a request", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.Read })},
+	{name: "read-header-timeout", usage: "Maximum duration of reading the headers of a request", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.ReadHeader })},
+	{name: "write-timeout", usage: "Maximum duration of writing a response", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.Write })},
+	{name: "idle-timeout", usage: "Maximum time idle keep-alive connections are kept open", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.Idle })},
+	{name: "proxy-timeout", usage: "Maximum duration of the requests forwarded to the upstream", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.Proxy })},
+	{name: "shutdown-timeout", usage: "Time the requests in flight have to finish when the server stops", set: duration(func(c *Config) *time.Duration { return &c.Timeouts.Shutdown })},
+}
+
+func str(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func boolean(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		*field(c) = b
+		return err
+	}
+}
+
+func list(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = nil
+		if v != "" {
+			*field(c) = strings.Split(v, ",")
+		}
+		return nil
+	}
+}
+
+func duration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		*field(c) = d
+		return err
+	}
+}
+
+// Load reads the configuration from the command line arguments, the
+// environment, looked up with getenv, and the file set with -config or
+// ECHO_CONFIG. Flags take precedence over environment variables, which take
+// precedence over the file. The configuration is validated before it's
+// returned, and every invalid value is reported.
+func Load(args []string, getenv func(string) (string, bool)) (*Config, error) {
+	fs := flag.NewFlagSet("echo", flag.ContinueOnError)
+	path := fs.String("config", "", "YAML or TOML configuration file. Also set with "+envPrefix+"CONFIG")
+	flags := map[*setting]string{}
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (%s)", s.usage, s.env())
+		record := func(v string) error {
+			flags[s] = v
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.name, usage, record)
+		} else {
+			fs.Func(s.name, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *path == "" {
+		*path, _ = getenv(envPrefix + "CONFIG")
+	}
+	if *path != "" {
+		if err := c.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	var errs []error
+	for _, s := range settings {
+		if v, ok := getenv(s.env()); ok {
+			if err := s.set(c, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %v", s.env(), err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flags[s]; ok {
+			if err := s.set(c, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid -%s: %v", s.name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile reads the configuration file, in TOML when its extension is
+// .toml and in YAML otherwise.
+func (c *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %v", err)
+	}
+	if filepath.Ext(path) == ".toml" {
+		if b, err = tomlToYAML(b); err != nil {
+			return fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// tomlToYAML converts a TOML document to YAML, so that both formats are
+// decoded with the same keys and rules.
+func tomlToYAML(doc []byte) ([]byte, error) {
+	m := map[string]any{}
+	if _, err := toml.Decode(string(doc), &m); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(m)
+}
+
+// Validate returns every error of the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	if err := store.NewValidator().Struct(c); err != nil {
+		errs = append(errs, err)
+	}
+	if err := store.NewValidator().Struct(c.ProxyConfig()); err != nil {
+		errs = append(errs, fmt.Errorf("invalid proxy configuration: %v", err))
+	}
+	if _, err := c.AuthConfig(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid auth configuration: %v", err))
+	}
+	if c.TLS.ClientCA != "" && c.TLS.Cert == "" && !c.TLS.Auto {
+		errs = append(errs, errors.New("client certificates need TLS: set a certificate or automatic TLS"))
+	}
+	if c.API.Addr == c.Addr {
+		errs = append(errs, fmt.Errorf("the API can't listen on %s, where the mock endpoints do", c.Addr))
+	}
+	return errors.Join(errs...)
+}
+
+// Seeded reports whether the storage is seeded on start.
+func (c *Config) Seeded() bool {
+	if c.Seed != nil {
+		return *c.Seed
+	}
+	return c.DB == ""
+}
+
+// Level returns the minimum level of the logs.
+func (c *Config) Level() slog.Level {
+	var l slog.Level
+	l.UnmarshalText([]byte(c.LogLevel)) //nolint:errcheck // validated by Validate
+	return l
+}
+
+// ProxyConfig returns the initial proxy configuration of the server.
+func (c *Config) ProxyConfig() server.ProxyConfig {
+	return server.ProxyConfig{
+		Mode:     server.ProxyMode(c.Proxy.Mode),
+		Upstream: c.Proxy.Upstream,
+		Key: server.RecordKey{
+			Query:   c.Proxy.RecordQuery,
+			Headers: c.Proxy.RecordHeaders,
+			Body:    c.Proxy.RecordBody,
+		},
+	}
+}
+
+// AuthConfig returns the credentials accepted by the API.
+func (c *Config) AuthConfig() (server.AuthConfig, error) {
+	a := server.AuthConfig{JWTSecret: c.Auth.JWTSecret, JWTScope: c.Auth.JWTScope}
+	if len(c.Auth.APIKeys) > 0 {
+		a.APIKeys = map[string]server.Grant{}
+		for _, k := range c.Auth.APIKeys {
+			key, grant, _ := strings.Cut(k, ":")
+			if key == "" {
+				return a, errors.New("API keys must be key[:role[:namespace+namespace...]]")
+			}
+			g, err := parseGrant(grant)
+			if err != nil {
+				return a, fmt.Errorf("invalid grant of API key: %v", err)
+			}
+			a.APIKeys[key] = g
+		}
+	}
+	if len(c.Auth.Users) > 0 {
+		a.Users = map[string]server.User{}
+		for _, u := range c.Auth.Users {
+			user, rest, ok := strings.Cut(u, ":")
+			if !ok || user == "" {
+				return a, errors.New("users must be user:password[:role[:namespace+namespace...]]")
+			}
+			password, grant, _ := strings.Cut(rest, ":")
+			g, err := parseGrant(grant)
+			if err != nil {
+				return a, fmt.Errorf("invalid grant of user %q: %v", user, err)
+			}
+			a.Users[user] = server.User{Password: password, Grant: g}
+		}
+	}
+	return a, nil
+}
+
+// parseGrant reads the grant of a credential, which is admin when it's not
+// set.
+func parseGrant(s string) (server.Grant, error) {
+	if s == "" {
+		return server.Grant{Role: server.Admin}, nil
+	}
+	return server.ParseGrant(s)
+}
+
+// TLSConfig returns the TLS configuration of the servers and the CA that
+// issues their certificates with automatic TLS. Both are nil when TLS is
+// disabled. The CA is generated, and kept in its directory, by this call.
+func (c *Config) TLSConfig() (*tls.Config, *certs.CA, error) {
+	t := c.TLS
+	if t.Cert == "" && !t.Auto {
+		return nil, nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	var ca *certs.CA
+	if t.Auto {
+		var err error
+		if t.CADir != "" {
+			ca, err = certs.LoadOrCreateCA(t.CADir)
+		} else {
+			ca, err = certs.NewCA()
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to set up the CA: %v", err)
+		}
+		cfg.GetCertificate = ca.GetCertificate
+	} else {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load the TLS certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if t.ClientCA != "" {
+		pem, err := os.ReadFile(t.ClientCA)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read the client CA: %v", err)
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("there are no certificates in %s", t.ClientCA)
+		}
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if t.ClientAuth == "require" {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, ca, nil
+}
+
+// Options returns the server options of the configuration.
+func (c *Config) Options() ([]server.Option, error) {
+	auth, err := c.AuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := []server.Option{
+		server.WithProxy(c.ProxyConfig()),
+		server.WithProxyTimeout(c.Timeouts.Proxy),
+		server.WithFixtures(c.Fixtures),
+		server.WithAuth(auth),
+		server.WithAPIPrefix(c.API.Prefix),
+	}
+	if c.Mocks != "" {
+		opts = append(opts, server.WithMocksDir(c.Mocks, c.MocksInterval))
+	}
+	return opts, nil
+}
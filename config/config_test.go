@@ -0,0 +1,273 @@
+package config
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/certs"
+	"github.com/Alvaroalonsobabbel/echo/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "echo.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c, err := Load(nil, env(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, Default(), c)
+		assert.True(t, c.Seeded())
+		assert.Equal(t, slog.LevelInfo, c.Level())
+	})
+
+	t.Run("flags override the environment, which overrides the file", func(t *testing.T) {
+		path := writeFile(t, `
+addr: ":4000"
+db: file.db
+logLevel: warn
+proxy:
+  recordHeaders: [Accept]
+timeouts:
+  read: 5s
+  write: 10s
+`)
+		c, err := Load([]string{"-config", path, "-db", "flag.db", "-seed", "-http2=false"}, env(map[string]string{
+			"ECHO_DB":            "env.db",
+			"ECHO_LOG_LEVEL":     "debug",
+			"ECHO_WRITE_TIMEOUT": "1m",
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, ":4000", c.Addr)
+		assert.Equal(t, "flag.db", c.DB)
+		assert.Equal(t, slog.LevelDebug, c.Level())
+		assert.Equal(t, []string{"Accept"}, c.Proxy.RecordHeaders)
+		assert.Equal(t, 5*time.Second, c.Timeouts.Read)
+		assert.Equal(t, time.Minute, c.Timeouts.Write)
+		assert.Equal(t, 2*time.Minute, c.Timeouts.Idle)
+		assert.False(t, c.HTTP2)
+		assert.True(t, c.Seeded())
+	})
+
+	t.Run("TOML files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "echo.toml")
+		assert.NoError(t, os.WriteFile(path, []byte(`
+addr = ":4000"
+seed = false
+
+[proxy]
+recordHeaders = ["Accept"]
+
+[timeouts]
+read = "5s"
+`), 0o600))
+		c, err := Load([]string{"-config", path}, env(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, ":4000", c.Addr)
+		assert.False(t, c.Seeded())
+		assert.Equal(t, []string{"Accept"}, c.Proxy.RecordHeaders)
+		assert.Equal(t, 5*time.Second, c.Timeouts.Read)
+
+		assert.NoError(t, os.WriteFile(path, []byte(`[proxy]
+recordheaders = ["Accept"]`), 0o600))
+		_, err = Load([]string{"-config", path}, env(nil))
+		assert.ErrorContains(t, err, "field recordheaders not found")
+		assert.NoError(t, os.WriteFile(path, []byte(`addr = `), 0o600))
+		_, err = Load([]string{"-config", path}, env(nil))
+		assert.ErrorContains(t, err, "invalid config file")
+	})
+
+	t.Run("the file is found in the environment", func(t *testing.T) {
+		path := writeFile(t, `db: file.db`)
+		c, err := Load(nil, env(map[string]string{"ECHO_CONFIG": path}))
+		assert.NoError(t, err)
+		assert.Equal(t, "file.db", c.DB)
+		assert.False(t, c.Seeded())
+	})
+
+	t.Run("auth", func(t *testing.T) {
+		c, err := Load([]string{"-api-keys", "k1,k2:viewer", "-basic-auth", "alice:pa55:editor:ci-1+ci-2"}, env(nil))
+		assert.NoError(t, err)
+		auth, err := c.AuthConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, server.AuthConfig{
+			APIKeys: map[string]server.Grant{"k1": {Role: server.Admin}, "k2": {Role: server.Viewer}},
+			Users:   map[string]server.User{"alice": {Password: "pa55", Grant: server.Grant{Role: server.Editor, Namespaces: []string{"ci-1", "ci-2"}}}},
+		}, auth)
+	})
+}
+
+func TestTLSConfig(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cfg, ca, err := Default().TLSConfig()
+		assert.NoError(t, err)
+		assert.Nil(t, cfg)
+		assert.Nil(t, ca)
+	})
+
+	t.Run("automatic with client certificates", func(t *testing.T) {
+		dir := t.TempDir()
+		clientCA, err := certs.LoadOrCreateCA(filepath.Join(dir, "clients"))
+		assert.NoError(t, err)
+		c, err := Load([]string{
+			"-tls-auto", "-tls-ca-dir", filepath.Join(dir, "ca"),
+			"-tls-client-ca", filepath.Join(dir, "clients", certs.CertFile), "-tls-client-auth", "require",
+		}, env(nil))
+		assert.NoError(t, err)
+		cfg, ca, err := c.TLSConfig()
+		assert.NoError(t, err)
+		assert.NotNil(t, cfg.GetCertificate)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+		assert.NotEqual(t, clientCA.PEM(), ca.PEM())
+
+		_, again, err := c.TLSConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, ca.PEM(), again.PEM(), "the CA is kept in its directory")
+	})
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		c, err := Load([]string{"-tls-cert", "config.go", "-tls-key", "config.go"}, env(nil))
+		assert.NoError(t, err)
+		_, _, err = c.TLSConfig()
+		assert.ErrorContains(t, err, "unable to load the TLS certificate")
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "unknown flag",
+			args:    []string{"-nope"},
+			wantErr: "flag provided but not defined: -nope",
+		},
+		{
+			name:    "invalid duration flag",
+			args:    []string{"-read-timeout", "soon"},
+			wantErr: `invalid -read-timeout: time: invalid duration "soon"`,
+		},
+		{
+			name:    "invalid environment variable",
+			env:     map[string]string{"ECHO_RECORD_BODY": "maybe"},
+			wantErr: `invalid ECHO_RECORD_BODY: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:    "unknown key in the file",
+			file:    "port: 3000",
+			wantErr: "field port not found in type config.Config",
+		},
+		{
+			name:    "invalid address",
+			args:    []string{"-addr", "3000"},
+			wantErr: "Field validation for 'Addr' failed on the 'hostname_port' tag",
+		},
+		{
+			name:    "invalid log level",
+			env:     map[string]string{"ECHO_LOG_LEVEL": "loud"},
+			wantErr: "Field validation for 'LogLevel' failed on the 'oneof' tag",
+		},
+		{
+			name:    "database with the memory backend",
+			args:    []string{"-storage", "memory", "-db", "echo.db"},
+			wantErr: "Field validation for 'DB' failed on the 'excluded_if' tag",
+		},
+		{
+			name:    "missing seed file",
+			args:    []string{"-seed-file", "nope.json"},
+			wantErr: "Field validation for 'SeedFile' failed on the 'file' tag",
+		},
+		{
+			name:    "negative timeout",
+			args:    []string{"-idle-timeout", "-1s"},
+			wantErr: "Field validation for 'Idle' failed on the 'gte' tag",
+		},
+		{
+			name:    "proxy without upstream",
+			args:    []string{"-proxy-mode", "record"},
+			wantErr: "invalid proxy configuration",
+		},
+		{
+			name:    "invalid role",
+			args:    []string{"-api-keys", "k1:owner"},
+			wantErr: `invalid auth configuration: invalid grant of API key: unknown role "owner"`,
+		},
+		{
+			name:    "missing mocks directory",
+			args:    []string{"-mocks", "nope"},
+			wantErr: "Field validation for 'Mocks' failed on the 'dir' tag",
+		},
+		{
+			name:    "zero mocks interval",
+			env:     map[string]string{"ECHO_MOCKS_INTERVAL": "0s"},
+			wantErr: "Field validation for 'MocksInterval' failed on the 'gt' tag",
+		},
+		{
+			name:    "key without certificate",
+			args:    []string{"-tls-key", "config.go"},
+			wantErr: "Field validation for 'Cert' failed on the 'required_with' tag",
+		},
+		{
+			name:    "certificate and automatic TLS",
+			args:    []string{"-tls-cert", "config.go", "-tls-key", "config.go", "-tls-auto"},
+			wantErr: "Field validation for 'Auto' failed on the 'excluded_with' tag",
+		},
+		{
+			name:    "CA directory without automatic TLS",
+			args:    []string{"-tls-ca-dir", "ca"},
+			wantErr: "Field validation for 'CADir' failed on the 'excluded_without' tag",
+		},
+		{
+			name:    "client certificates without TLS",
+			args:    []string{"-tls-client-ca", "config.go", "-tls-client-auth", "require"},
+			wantErr: "client certificates need TLS",
+		},
+		{
+			name:    "invalid client auth",
+			args:    []string{"-tls-auto", "-tls-client-ca", "config.go", "-tls-client-auth", "always"},
+			wantErr: "Field validation for 'ClientAuth' failed on the 'oneof' tag",
+		},
+		{
+			name:    "API on the address of the mocks",
+			args:    []string{"-api-addr", ":3000"},
+			wantErr: "the API can't listen on :3000, where the mock endpoints do",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, test.file))
+			}
+			_, err := Load(args, env(test.env))
+			assert.ErrorContains(t, err, test.wantErr)
+		})
+	}
+
+	t.Run("every validation error is reported", func(t *testing.T) {
+		_, err := Load([]string{"-addr", "nope", "-log-level", "loud", "-api-keys", ":viewer"}, env(nil))
+		assert.ErrorContains(t, err, "'Addr'")
+		assert.ErrorContains(t, err, "'LogLevel'")
+		assert.ErrorContains(t, err, "invalid auth configuration")
+	})
+}
@@ -0,0 +1,203 @@
+// Package har exports echo endpoints as HTTP Archive (HAR 1.2) files, which
+// most browsers and HTTP tools can open.
+package har
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// archive is the root of a HAR file.
+type archive struct {
+	Log struct {
+		Version string   `json:"version"`
+		Creator creator  `json:"creator"`
+		Entries []*entry `json:"entries"`
+	} `json:"log"`
+}
+
+type creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// entry is an exchange between a client and an endpoint.
+type entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            int       `json:"time"`
+	Request         request   `json:"request"`
+	Response        response  `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         timings   `json:"timings"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+type request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []nameValue `json:"cookies"`
+	Headers     []nameValue `json:"headers"`
+	QueryString []nameValue `json:"queryString"`
+	PostData    *postData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type postData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []cookie    `json:"cookies"`
+	Headers     []nameValue `json:"headers"`
+	Content     content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+type nameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type timings struct {
+	Send    int `json:"send"`
+	Wait    int `json:"wait"`
+	Receive int `json:"receive"`
+}
+
+// Export returns a HAR file with an entry for each endpoint, as if it had been
+// requested from base at the given time. Requests carry the values the
+// endpoint's matchers expect, when they are known, and path templates are
+// kept as they are. Sequences are answered with their first response.
+func Export(endpoints []*store.Endpoint, base string, now time.Time) ([]byte, error) {
+	a := &archive{}
+	a.Log.Version = "1.2"
+	a.Log.Creator = creator{Name: "echo", Version: "1.0.0"}
+	a.Log.Entries = []*entry{}
+	for _, e := range endpoints {
+		a.Log.Entries = append(a.Log.Entries, newEntry(e, base, now))
+	}
+	return json.Marshal(a)
+}
+
+func newEntry(e *store.Endpoint, base string, now time.Time) *entry {
+	a := e.Attributes
+	req := request{
+		Method:      a.Verb,
+		URL:         strings.TrimSuffix(base, "/") + a.Path,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []nameValue{},
+		Headers:     []nameValue{},
+		QueryString: []nameValue{},
+		HeadersSize: -1,
+	}
+	if m := a.Match; m != nil {
+		query := url.Values{}
+		for _, k := range sortedKeys(m.Query) {
+			if v := m.Query[k].Equals; v != "" {
+				req.QueryString = append(req.QueryString, nameValue{Name: k, Value: v})
+				query.Add(k, v)
+			}
+		}
+		if len(query) > 0 {
+			req.URL += "?" + query.Encode()
+		}
+		for _, k := range sortedKeys(m.Headers) {
+			if v := m.Headers[k].Equals; v != "" {
+				req.Headers = append(req.Headers, nameValue{Name: k, Value: v})
+			}
+		}
+		if m.Body != nil && m.Body.JSON != nil {
+			if b, err := json.Marshal(m.Body.JSON); err == nil {
+				req.PostData = &postData{MimeType: "application/json", Text: string(b)}
+				req.BodySize = len(b)
+			}
+		}
+	}
+
+	r := a.Responses()[0]
+	res := response{
+		Status:      r.Code,
+		StatusText:  http.StatusText(r.Code),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []cookie{},
+		Headers:     []nameValue{},
+		Content:     content{Size: len(r.Body), Text: r.Body},
+		HeadersSize: -1,
+		BodySize:    len(r.Body),
+	}
+	switch {
+	case len(r.JSON) > 0:
+		res.Content = content{Size: len(r.JSON), MimeType: "application/json", Text: string(r.JSON)}
+		res.BodySize = len(r.JSON)
+	case r.Encoding == store.Base64Encoding:
+		res.Content.Encoding = "base64"
+	}
+	for _, k := range sortedKeys(r.Headers) {
+		for _, v := range r.Headers[k] {
+			res.Headers = append(res.Headers, nameValue{Name: k, Value: v})
+		}
+	}
+	if t := r.Headers.Get("Content-Type"); t != "" {
+		res.Content.MimeType = t
+	}
+	res.RedirectURL = r.Headers.Get("Location")
+	for _, c := range r.Cookies {
+		res.Headers = append(res.Headers, nameValue{Name: "Set-Cookie", Value: c.HTTP().String()})
+		res.Cookies = append(res.Cookies, cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		})
+	}
+
+	return &entry{
+		StartedDateTime: now,
+		Request:         req,
+		Response:        res,
+		Comment:         a.Verb + " " + a.Path,
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,72 @@
+package har
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	endpoints := []*store.Endpoint{
+		{ID: 1, Type: "endpoints", Attributes: store.Attributes{
+			Verb: "POST", Path: "/orders",
+			Match: &store.Matchers{
+				Query:   map[string]store.ValueMatcher{"dry": {Equals: "true"}, "v": {Matches: "^[0-9]+$"}},
+				Headers: map[string]store.ValueMatcher{"X-Tenant": {Equals: "acme"}},
+				Body:    &store.BodyMatcher{JSON: map[string]any{"sku": "A1"}},
+			},
+			Response: store.Response{
+				Code:    201,
+				Headers: store.Headers{"Content-Type": {"application/json"}, "Location": {"/orders/1"}, "Vary": {"Accept", "Origin"}},
+				Cookies: []store.Cookie{{Name: "cart", Value: "A1", Path: "/", HttpOnly: true}},
+				JSON:    store.JSONValue(`{"id":1}`),
+			},
+		}},
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	doc, err := Export(endpoints, "http://localhost:3000/", now)
+	assert.NoError(t, err)
+	want := `{"log": {
+		"version": "1.2",
+		"creator": {"name": "echo", "version": "1.0.0"},
+		"entries": [{
+			"startedDateTime": "2024-01-01T00:00:00Z",
+			"time": 0,
+			"comment": "POST /orders",
+			"request": {
+				"method": "POST",
+				"url": "http://localhost:3000/orders?dry=true",
+				"httpVersion": "HTTP/1.1",
+				"cookies": [],
+				"headers": [{"name": "X-Tenant", "value": "acme"}],
+				"queryString": [{"name": "dry", "value": "true"}],
+				"postData": {"mimeType": "application/json", "text": "{\"sku\":\"A1\"}"},
+				"headersSize": -1,
+				"bodySize": 12
+			},
+			"response": {
+				"status": 201,
+				"statusText": "Created",
+				"httpVersion": "HTTP/1.1",
+				"cookies": [{"name": "cart", "value": "A1", "path": "/", "httpOnly": true}],
+				"headers": [
+					{"name": "Content-Type", "value": "application/json"},
+					{"name": "Location", "value": "/orders/1"},
+					{"name": "Vary", "value": "Accept"},
+					{"name": "Vary", "value": "Origin"},
+					{"name": "Set-Cookie", "value": "cart=A1; Path=/; HttpOnly"}
+				],
+				"content": {"size": 8, "mimeType": "application/json", "text": "{\"id\":1}"},
+				"redirectURL": "/orders/1",
+				"headersSize": -1,
+				"bodySize": 8
+			},
+			"cache": {},
+			"timings": {"send": 0, "wait": 0, "receive": 0}
+		}]
+	}}`
+	assert.JSONEq(t, want, string(doc))
+}
@@ -0,0 +1,147 @@
+// Package journal records the requests served by the mock endpoints so they
+// can be inspected and verified later on.
+package journal
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// DefaultLimit is the number of entries kept by a journal created with a
+// limit of zero.
+const DefaultLimit = 1000
+
+// Entry is a recorded request. EndpointID is zero for requests that didn't
+// match any endpoint.
+type Entry struct {
+	ID        int         `json:"-"`
+	Namespace string      `json:"namespace,omitempty"`
+	Method    string      `json:"method"`
+	Path      string      `json:"path"`
+	Query     url.Values  `json:"query"`
+	Headers   http.Header `json:"headers"`
+	Body      string      `json:"body"`
+	// Protocol is the protocol version of the request, like HTTP/2.0.
+	Protocol string `json:"protocol"`
+	// ClientCert is the subject of the client certificate, if any.
+	ClientCert string    `json:"clientCert,omitempty"`
+	EndpointID int       `json:"endpointId,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Filter selects journal entries. Zero fields match every entry, but for
+// Namespace: entries are only selected within a namespace.
+type Filter struct {
+	// Namespace is set by the server to the namespace of the client.
+	Namespace string `json:"-"`
+	Method    string `json:"method"`
+	// Path is a path template, so `/users/{id}` selects requests to any user.
+	Path       string          `json:"path" validate:"omitempty,pathtemplate"`
+	EndpointID int             `json:"endpointId"`
+	Matched    *bool           `json:"matched"`
+	Match      *store.Matchers `json:"match"`
+}
+
+// Journal keeps the latest requests in memory. It's safe for concurrent use.
+type Journal struct {
+	mu      sync.RWMutex
+	limit   int
+	lastID  int
+	entries []*Entry
+}
+
+// New returns a journal that keeps up to limit entries, dropping the oldest
+// ones once it's full.
+func New(limit int) *Journal {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Journal{limit: limit}
+}
+
+// Record adds the entry to the journal, setting its ID.
+func (j *Journal) Record(e *Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastID++
+	e.ID = j.lastID
+	if len(j.entries) == j.limit {
+		j.entries = j.entries[1:]
+	}
+	j.entries = append(j.entries, e)
+}
+
+// Find returns the entries selected by the filter, oldest first.
+func (j *Journal) Find(f *Filter) []*Entry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	found := []*Entry{}
+	for _, e := range j.entries {
+		if f.matches(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Count returns the number of entries selected by the filter.
+func (j *Journal) Count(f *Filter) int {
+	return len(j.Find(f))
+}
+
+// Delete removes the entries selected by the filter.
+func (j *Journal) Delete(f *Filter) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	kept := []*Entry{}
+	for _, e := range j.entries {
+		if !f.matches(e) {
+			kept = append(kept, e)
+		}
+	}
+	j.entries = kept
+}
+
+// Reset removes every entry from the journal.
+func (j *Journal) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = nil
+}
+
+func (f *Filter) matches(e *Entry) bool {
+	if f.Namespace != e.Namespace {
+		return false
+	}
+	if f.Method != "" && f.Method != e.Method {
+		return false
+	}
+	if f.Path != "" {
+		if _, ok := store.MatchPath(f.Path, e.Path); !ok {
+			return false
+		}
+	}
+	if f.EndpointID != 0 && f.EndpointID != e.EndpointID {
+		return false
+	}
+	if f.Matched != nil && *f.Matched != (e.EndpointID != 0) {
+		return false
+	}
+	return f.Match.Matches(&store.Request{
+		Method:     e.Method,
+		Path:       e.Path,
+		Query:      e.Query,
+		Headers:    e.Headers,
+		Body:       []byte(e.Body),
+		ClientCert: e.ClientCert,
+		Protocol:   e.Protocol,
+	})
+}
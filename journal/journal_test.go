@@ -0,0 +1,78 @@
+package journal
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	j := New(3)
+	j.Record(&Entry{Method: http.MethodGet, Path: "/dropped"})
+	j.Record(&Entry{Method: http.MethodPost, Path: "/payments", Body: `{"amount":10}`, EndpointID: 1})
+	j.Record(&Entry{Method: http.MethodPost, Path: "/payments", Body: `{"amount":20}`, EndpointID: 1})
+	j.Record(&Entry{Method: http.MethodGet, Path: "/users/42", Query: url.Values{"page": {"2"}}, ClientCert: "CN=ci", Protocol: "HTTP/2.0"})
+
+	matched, unmatched := true, false
+	tests := []struct {
+		name    string
+		filter  *Filter
+		wantIDs []int
+	}{
+		{name: "empty filter returns the latest entries", filter: &Filter{}, wantIDs: []int{2, 3, 4}},
+		{name: "method", filter: &Filter{Method: http.MethodPost}, wantIDs: []int{2, 3}},
+		{name: "path template", filter: &Filter{Path: "/users/{id}"}, wantIDs: []int{4}},
+		{name: "endpoint", filter: &Filter{EndpointID: 1}, wantIDs: []int{2, 3}},
+		{name: "matched", filter: &Filter{Matched: &matched}, wantIDs: []int{2, 3}},
+		{name: "unmatched", filter: &Filter{Matched: &unmatched}, wantIDs: []int{4}},
+		{
+			name:    "body",
+			filter:  &Filter{Method: http.MethodPost, Path: "/payments", Match: &store.Matchers{Body: &store.BodyMatcher{JSON: map[string]any{"amount": 10.0}}}},
+			wantIDs: []int{2},
+		},
+		{
+			name:    "query",
+			filter:  &Filter{Match: &store.Matchers{Query: map[string]store.ValueMatcher{"page": {Equals: "2"}}}},
+			wantIDs: []int{4},
+		},
+		{
+			name:    "protocol",
+			filter:  &Filter{Match: &store.Matchers{Protocol: &store.ValueMatcher{Equals: "HTTP/2.0"}}},
+			wantIDs: []int{4},
+		},
+		{
+			name:    "client certificate",
+			filter:  &Filter{Match: &store.Matchers{ClientCert: &store.ValueMatcher{Equals: "CN=ci"}}},
+			wantIDs: []int{4},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := []int{}
+			for _, e := range j.Find(test.filter) {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, test.wantIDs, ids)
+			assert.Equal(t, len(test.wantIDs), j.Count(test.filter))
+		})
+	}
+
+	t.Run("namespaces", func(t *testing.T) {
+		j.Record(&Entry{Namespace: "ci-1", Method: http.MethodGet, Path: "/users/7"})
+		assert.Len(t, j.Find(&Filter{Path: "/users/{id}"}), 1)
+		assert.Len(t, j.Find(&Filter{Namespace: "ci-1"}), 1)
+
+		j.Delete(&Filter{Namespace: "ci-1"})
+		assert.Empty(t, j.Find(&Filter{Namespace: "ci-1"}))
+		assert.Len(t, j.Find(&Filter{}), 2)
+	})
+
+	t.Run("Reset removes every entry", func(t *testing.T) {
+		j.Reset()
+		assert.Empty(t, j.Find(&Filter{}))
+	})
+}
@@ -1,25 +1,190 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Alvaroalonsobabbel/echo/config"
+	"github.com/Alvaroalonsobabbel/echo/openapi"
 	"github.com/Alvaroalonsobabbel/echo/server"
 	"github.com/Alvaroalonsobabbel/echo/store"
 )
 
-const port = ":3000"
+// reapInterval is how often expired endpoints are deleted.
+const reapInterval = time.Second
 
 func main() {
-	store, err := store.New()
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := importOpenAPI(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves until SIGINT or SIGTERM, then drains the requests in flight and
+// closes the storage.
+func run() error {
+	c, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: c.Level()})))
+	opts, err := c.Options()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+	tlsConfig, ca, err := c.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+	if ca != nil {
+		opts = append(opts, server.WithCA(ca.PEM()))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := newStorage(c.Storage, c.DB)
 	if err != nil {
-		log.Fatalf("unable to initialize storage: %v", err)
+		return fmt.Errorf("unable to initialize storage: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("unable to close the storage", "err", err)
+		}
+	}()
+	if c.Seeded() {
+		if err := seed(store, c.SeedFile); err != nil {
+			return fmt.Errorf("unable to seed the DB: %v", err)
+		}
+	}
+
+	opts = append(opts, server.WithReaper(ctx, reapInterval))
+	var (
+		srv     *server.Server
+		servers []*http.Server
+	)
+	if c.API.Addr == "" {
+		srv = server.New(store, opts...)
+		servers = append(servers, httpServer(c.Addr, srv, c.Timeouts, tlsConfig))
+	} else {
+		var api http.Handler
+		srv, api = server.NewSplit(store, opts...)
+		servers = append(servers, httpServer(c.Addr, srv, c.Timeouts, tlsConfig), httpServer(c.API.Addr, api, c.Timeouts, tlsConfig))
+		slog.Info("starting API", "addr", c.API.Addr)
+	}
+	defer srv.Close()
+	for _, s := range servers {
+		if !c.HTTP2 {
+			server.DisableHTTP2(s)
+			continue
+		}
+		if err := server.EnableHTTP2(s); err != nil {
+			return fmt.Errorf("unable to set up HTTP/2: %v", err)
+		}
 	}
-	defer store.Close()
-	if err := store.Seed(); err != nil {
-		log.Fatalf("unable to seed the DB: %v", err)
+
+	slog.Info("starting server", "addr", c.Addr, "tls", tlsConfig != nil, "http2", c.HTTP2)
+	return server.Serve(ctx, c.Timeouts.Shutdown, servers...)
+}
+
+func httpServer(addr string, h http.Handler, t config.Timeouts, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       t.Read,
+		ReadHeaderTimeout: t.ReadHeader,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
 	}
+}
 
-	log.Printf("Starting server on port %s", port)
-	log.Fatal(http.ListenAndServe(port, server.New(store)))
+// seed creates the endpoints of the bundle in file or, when it's empty, the
+// built-in ones. Endpoints that already exist are skipped, so persistent
+// databases can be seeded on every start.
+func seed(s store.Storage, file string) error {
+	if file == "" {
+		return s.Seed()
+	}
+	doc, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	endpoints, err := server.ReadBundle(doc)
+	if err != nil {
+		return err
+	}
+	created, skipped, err := store.CreateEndpoints(s, endpoints)
+	if err != nil {
+		return err
+	}
+	for _, err := range skipped {
+		slog.Warn("skipped seed endpoint", "err", err)
+	}
+	slog.Info("seeded endpoints", "file", file, "count", len(created))
+	return nil
+}
+
+func newStorage(backend, dsn string) (store.Storage, error) {
+	switch backend {
+	case "sqlite":
+		return store.New(dsn)
+	case "memory":
+		return store.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// importOpenAPI implements `echo import -db <database> <document>`, which
+// creates an endpoint for each operation of an OpenAPI 3 document.
+func importOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dsn := fs.String("db", "", "SQLite database file or DSN to import the endpoints into")
+	fs.Parse(args) //nolint:errcheck // ExitOnError
+	if *dsn == "" || fs.NArg() != 1 {
+		return errors.New("usage: echo import -db <database> <openapi document>")
+	}
+
+	doc, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("unable to read OpenAPI document: %v", err)
+	}
+	endpoints, err := openapi.Import(doc)
+	if err != nil {
+		return err
+	}
+	s, err := store.New(*dsn)
+	if err != nil {
+		return fmt.Errorf("unable to initialize storage: %v", err)
+	}
+	defer s.Close()
+
+	created, skipped, err := store.CreateEndpoints(s, endpoints)
+	if err != nil {
+		return fmt.Errorf("unable to import endpoints: %v", err)
+	}
+	for _, err := range skipped {
+		log.Printf("skipped %v", err)
+	}
+	log.Printf("imported %d endpoints", len(created))
+	return nil
 }
@@ -0,0 +1,114 @@
+// Package mockfile reads endpoint definitions from a directory of YAML and
+// JSON files, so that mocks can be kept in version control next to the code
+// that uses them.
+package mockfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"gopkg.in/yaml.v3"
+)
+
+// extensions are those of the definition files. Other files are ignored.
+var extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// File is a definition file of the directory.
+type File struct {
+	// Path is the path of the file relative to the directory, with forward
+	// slashes.
+	Path string
+	// Sum is the SHA-256 of the contents of the file, which tells when it
+	// changed.
+	Sum [sha256.Size]byte
+	// Endpoints are the endpoints defined in the file, unless Err is set.
+	Endpoints []*store.Endpoint
+	// Err is why the file couldn't be read or parsed.
+	Err error
+}
+
+// Read returns the definition files of dir and its subdirectories, sorted by
+// path. Hidden files and directories, like .git, are skipped. The error is
+// only set when the directory can't be walked: the errors of each file are
+// in its Err.
+func Read(dir string) ([]*File, error) {
+	files := []*File{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !extensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f := &File{Path: filepath.ToSlash(rel)}
+		doc, err := os.ReadFile(path)
+		if err != nil {
+			f.Err = err
+		} else {
+			f.Sum = sha256.Sum256(doc)
+			f.Endpoints, f.Err = Parse(doc)
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Parse reads the endpoints of a definition file, in YAML or JSON. A file
+// holds an endpoint or a list of them, each written as the attributes of an
+// endpoint of the API. Unknown attributes are rejected, so that typos don't
+// go unnoticed.
+func Parse(doc []byte) ([]*store.Endpoint, error) {
+	var raw any
+	if err := yaml.Unmarshal(doc, &raw); err != nil {
+		return nil, err
+	}
+	var definitions []any
+	switch v := store.NormalizeYAML(raw).(type) {
+	case nil:
+		return []*store.Endpoint{}, nil
+	case map[string]any:
+		definitions = []any{v}
+	case []any:
+		definitions = v
+	default:
+		return nil, errors.New("expected an endpoint or a list of endpoints")
+	}
+
+	endpoints := make([]*store.Endpoint, 0, len(definitions))
+	for i, d := range definitions {
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %d: %v", i+1, err)
+		}
+		e := &store.Endpoint{Type: "endpoints"}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e.Attributes); err != nil {
+			return nil, fmt.Errorf("endpoint %d: %v", i+1, err)
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,111 @@
+package mockfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    []store.Attributes
+		wantErr string
+	}{
+		{
+			name: "single endpoint in YAML",
+			doc: `
+verb: GET
+path: /users/{id}
+response:
+  code: 200
+  json: {id: 1, name: Ada}
+`,
+			want: []store.Attributes{{
+				Verb: "GET", Path: "/users/{id}",
+				Response: store.Response{Code: 200, JSON: store.JSONValue(`{"id":1,"name":"Ada"}`)},
+			}},
+		},
+		{
+			name: "list of endpoints in JSON",
+			doc:  `[{"verb": "GET", "path": "/a", "response": {"code": 200}}, {"verb": "DELETE", "path": "/a", "response": {"code": 204}, "namespace": "ci"}]`,
+			want: []store.Attributes{
+				{Verb: "GET", Path: "/a", Response: store.Response{Code: 200}},
+				{Namespace: "ci", Verb: "DELETE", Path: "/a", Response: store.Response{Code: 204}},
+			},
+		},
+		{
+			name: "empty file",
+			doc:  "# nothing yet\n",
+			want: []store.Attributes{},
+		},
+		{
+			name:    "invalid YAML",
+			doc:     "verb: [GET",
+			wantErr: "yaml: line 1",
+		},
+		{
+			name:    "scalar document",
+			doc:     "GET /a",
+			wantErr: "expected an endpoint or a list of endpoints",
+		},
+		{
+			name:    "unknown attribute",
+			doc:     "- verb: GET\n  path: /a\n  response: {code: 200}\n- verb: GET\n  pth: /b",
+			wantErr: `endpoint 2: json: unknown field "pth"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			endpoints, err := Parse([]byte(test.doc))
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			got := []store.Attributes{}
+			for _, e := range endpoints {
+				assert.Equal(t, "endpoints", e.Type)
+				got = append(got, e.Attributes)
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.yaml":         "verb: GET\npath: /users\nresponse: {code: 200}",
+		"orders/orders.json": `{"verb": "GET", "path": "/orders", "response": {"code": 200}}`,
+		"broken.yml":         "verb: [GET",
+		"README.md":          "# mocks",
+		".git/config.yaml":   "verb: GET",
+		".draft.yaml":        "verb: GET",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	got, err := Read(dir)
+	assert.NoError(t, err)
+	var paths []string
+	for _, f := range got {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"broken.yml", "orders/orders.json", "users.yaml"}, paths)
+	assert.ErrorContains(t, got[0].Err, "yaml")
+	assert.Len(t, got[1].Endpoints, 1)
+	assert.Equal(t, "/users", got[2].Endpoints[0].Attributes.Path)
+	assert.NotEqual(t, got[1].Sum, got[2].Sum)
+
+	_, err = Read(filepath.Join(dir, "nope"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// Export returns an OpenAPI 3 document, in JSON, describing the endpoints.
+// Endpoints sharing a verb and path, like those with different matchers, are
+// described by the same operation with an example for each of them, and so
+// are the responses of a sequence.
+func Export(endpoints []*store.Endpoint) ([]byte, error) {
+	paths := map[string]map[string]any{}
+	for _, e := range endpoints {
+		path, params := exportPath(e.Attributes.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		method := strings.ToLower(e.Attributes.Verb)
+		op, ok := paths[path][method].(map[string]any)
+		if !ok {
+			op = map[string]any{
+				"operationId": fmt.Sprintf("endpoint%d", e.ID),
+				"responses":   map[string]any{},
+			}
+			if len(params) > 0 {
+				op["parameters"] = params
+			}
+			paths[path][method] = op
+		}
+		for i, r := range e.Attributes.Responses() {
+			name := fmt.Sprintf("endpoint%d", e.ID)
+			if i > 0 {
+				name += fmt.Sprintf("-%d", i+1)
+			}
+			addResponse(op["responses"].(map[string]any), name, *r)
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "echo",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	})
+}
+
+// exportPath converts a path template to an OpenAPI path and its parameters.
+// Wildcards have no equivalent, so they become a `wildcard` parameter.
+func exportPath(template string) (string, []any) {
+	parts := strings.Split(template, "/")
+	params := []any{}
+	for i, p := range parts {
+		name := ""
+		switch {
+		case p == "*":
+			name = "wildcard"
+			parts[i] = "{wildcard}"
+		case len(p) > 2 && strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+			name = p[1 : len(p)-1]
+		default:
+			continue
+		}
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func addResponse(responses map[string]any, name string, r store.Response) {
+	code := strconv.Itoa(r.Code)
+	res, ok := responses[code].(map[string]any)
+	if !ok {
+		res = map[string]any{"description": http.StatusText(r.Code)}
+		responses[code] = res
+	}
+
+	contentType := ""
+	for _, k := range sortedHeaders(r.Headers) {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = r.Headers.Get(k)
+			continue
+		}
+		headers, _ := res["headers"].(map[string]any)
+		if headers == nil {
+			headers = map[string]any{}
+			res["headers"] = headers
+		}
+		headers[k] = map[string]any{
+			"schema":  map[string]any{"type": "string"},
+			"example": strings.Join(r.Headers[k], ", "),
+		}
+	}
+	if r.Body == "" && len(r.JSON) == 0 {
+		return
+	}
+	if contentType == "" {
+		contentType = "text/plain"
+		if len(r.JSON) > 0 {
+			contentType = "application/json"
+		}
+	}
+
+	var example any = r.Body
+	if len(r.JSON) > 0 {
+		var v any
+		if err := json.Unmarshal(r.JSON, &v); err == nil {
+			example = v
+		}
+	} else if strings.Contains(contentType, "json") {
+		var v any
+		if err := json.Unmarshal([]byte(r.Body), &v); err == nil {
+			example = v
+		}
+	}
+	content, _ := res["content"].(map[string]any)
+	if content == nil {
+		content = map[string]any{}
+		res["content"] = content
+	}
+	media, _ := content[contentType].(map[string]any)
+	if media == nil {
+		media = map[string]any{"examples": map[string]any{}}
+		content[contentType] = media
+	}
+	media["examples"].(map[string]any)[name] = map[string]any{"value": example}
+}
+
+func sortedHeaders(h store.Headers) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	endpoints := []*store.Endpoint{
+		{ID: 1, Type: "endpoints", Attributes: store.Attributes{
+			Verb: "GET", Path: "/pets/{id}",
+			Response: store.Response{Code: 200, Headers: store.Headers{"Content-Type": {"application/json"}, "X-Rate-Limit": {"10"}}, JSON: store.JSONValue(`{"name":"Rex"}`)},
+		}},
+		{ID: 2, Type: "endpoints", Attributes: store.Attributes{
+			Verb: "GET", Path: "/pets/{id}",
+			Match:    &store.Matchers{Query: map[string]store.ValueMatcher{"missing": {Equals: "true"}}},
+			Response: store.Response{Code: 404, Headers: store.Headers{"Content-Type": {"text/plain"}}, Body: "not found"},
+		}},
+		{ID: 3, Type: "endpoints", Attributes: store.Attributes{
+			Verb: "DELETE", Path: "/static/*",
+			Response: store.Response{Code: 204},
+		}},
+	}
+
+	doc, err := Export(endpoints)
+	assert.NoError(t, err)
+
+	t.Run("describes every endpoint", func(t *testing.T) {
+		want := `{
+			"openapi": "3.0.3",
+			"info": {"title": "echo", "version": "1.0.0"},
+			"paths": {
+				"/pets/{id}": {
+					"get": {
+						"operationId": "endpoint1",
+						"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+						"responses": {
+							"200": {
+								"description": "OK",
+								"headers": {"X-Rate-Limit": {"schema": {"type": "string"}, "example": "10"}},
+								"content": {"application/json": {"examples": {"endpoint1": {"value": {"name": "Rex"}}}}}
+							},
+							"404": {
+								"description": "Not Found",
+								"content": {"text/plain": {"examples": {"endpoint2": {"value": "not found"}}}}
+							}
+						}
+					}
+				},
+				"/static/{wildcard}": {
+					"delete": {
+						"operationId": "endpoint3",
+						"parameters": [{"name": "wildcard", "in": "path", "required": true, "schema": {"type": "string"}}],
+						"responses": {"204": {"description": "No Content"}}
+					}
+				}
+			}
+		}`
+		assert.JSONEq(t, want, string(doc))
+	})
+
+	t.Run("can be imported back", func(t *testing.T) {
+		imported, err := Import(doc)
+		assert.NoError(t, err)
+		assert.Len(t, imported, 2)
+		var body any
+		assert.NoError(t, json.Unmarshal(imported[0].Attributes.Response.JSON, &body))
+		assert.Equal(t, map[string]any{"name": "Rex"}, body)
+		assert.Equal(t, "/pets/{id}", imported[0].Attributes.Path)
+	})
+}
@@ -0,0 +1,246 @@
+// Package openapi converts between OpenAPI 3 documents and echo endpoints.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSampleDepth limits how deep schemas are followed when generating sample
+// bodies, which avoids looping forever on recursive schemas.
+const maxSampleDepth = 8
+
+// methods are the operations of a path item, in the order they are imported.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Import reads an OpenAPI 3.x document, in YAML or JSON, and returns an
+// endpoint for each of its operations. Responses use the first success status
+// code, with a body taken from the declared examples or generated from the
+// schema of its content.
+func Import(doc []byte) ([]*store.Endpoint, error) {
+	var raw any
+	if err := yaml.Unmarshal(doc, &raw); err != nil {
+		return nil, fmt.Errorf("unable to parse OpenAPI document: %v", err)
+	}
+	spec, _ := store.NormalizeYAML(raw).(map[string]any)
+	version, _ := spec["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", version)
+	}
+	d := &document{spec: spec}
+
+	paths, _ := spec["paths"].(map[string]any)
+	endpoints := []*store.Endpoint{}
+	for _, path := range sortedKeys(paths) {
+		item, _ := d.resolve(paths[path]).(map[string]any)
+		for _, method := range methods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			endpoints = append(endpoints, &store.Endpoint{
+				Type: "endpoints",
+				Attributes: store.Attributes{
+					Verb:     strings.ToUpper(method),
+					Path:     path,
+					Response: d.response(op),
+				},
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+type document struct {
+	spec map[string]any
+}
+
+// response builds the response of an operation from its first success code.
+func (d *document) response(op map[string]any) store.Response {
+	responses, _ := op["responses"].(map[string]any)
+	code, key := successCode(responses)
+	res := store.Response{Code: code, Headers: store.Headers{}}
+
+	r, _ := d.resolve(responses[key]).(map[string]any)
+	content, _ := r["content"].(map[string]any)
+	if len(content) == 0 {
+		return res
+	}
+	mediaType := sortedKeys(content)[0]
+	if _, ok := content["application/json"]; ok {
+		mediaType = "application/json"
+	}
+	res.Headers["Content-Type"] = []string{mediaType}
+
+	media, _ := content[mediaType].(map[string]any)
+	body, ok := d.example(media)
+	if !ok {
+		return res
+	}
+	if s, ok := body.(string); ok && !strings.Contains(mediaType, "json") {
+		res.Body = s
+		return res
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return res
+	}
+	if strings.Contains(mediaType, "json") {
+		res.JSON = b
+	} else {
+		res.Body = string(b)
+	}
+	return res
+}
+
+// successCode returns the lowest 2xx code of the responses and its key. It
+// falls back to the lowest declared code and then to 200.
+func successCode(responses map[string]any) (int, string) {
+	best, bestKey := 0, ""
+	for key := range responses {
+		code, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		success := code >= 200 && code < 300
+		bestSuccess := best >= 200 && best < 300
+		if best == 0 || (success && !bestSuccess) || (success == bestSuccess && code < best) {
+			best, bestKey = code, key
+		}
+	}
+	if best == 0 {
+		return http.StatusOK, "default"
+	}
+	return best, bestKey
+}
+
+// example returns the example of a media type, trying the example, the
+// first of the examples and then the schema.
+func (d *document) example(media map[string]any) (any, bool) {
+	if ex, ok := media["example"]; ok {
+		return ex, true
+	}
+	if examples, ok := media["examples"].(map[string]any); ok && len(examples) > 0 {
+		ex, _ := d.resolve(examples[sortedKeys(examples)[0]]).(map[string]any)
+		if v, ok := ex["value"]; ok {
+			return v, true
+		}
+	}
+	if schema, ok := media["schema"]; ok {
+		return d.sample(schema, 0), true
+	}
+	return nil, false
+}
+
+// sample generates a value that conforms to the schema.
+func (d *document) sample(s any, depth int) any {
+	schema, _ := d.resolve(s).(map[string]any)
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+	for _, k := range []string{"example", "default"} {
+		if v, ok := schema[k]; ok {
+			return v
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		merged := map[string]any{}
+		for _, sub := range all {
+			if obj, ok := d.sample(sub, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, k := range []string{"oneOf", "anyOf"} {
+		if subs, ok := schema[k].([]any); ok && len(subs) > 0 {
+			return d.sample(subs[0], depth+1)
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ == "" {
+		if _, ok := schema["properties"]; ok {
+			typ = "object"
+		}
+	}
+	switch typ {
+	case "object":
+		obj := map[string]any{}
+		props, _ := schema["properties"].(map[string]any)
+		for name, prop := range props {
+			obj[name] = d.sample(prop, depth+1)
+		}
+		return obj
+	case "array":
+		return []any{d.sample(schema["items"], depth+1)}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return true
+	case "string":
+		return sampleString(schema)
+	}
+	return nil
+}
+
+func sampleString(schema map[string]any) string {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "uuid":
+		return "00000000-0000-4000-8000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+	return "string"
+}
+
+// resolve follows local references (`#/components/...`) until it finds the
+// referenced value.
+func (d *document) resolve(v any) any {
+	for i := 0; i < maxSampleDepth; i++ {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var target any = d.spec
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+			obj, _ := target.(map[string]any)
+			target = obj[part]
+		}
+		v = target
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImport(t *testing.T) {
+	doc, err := os.ReadFile("testdata/petstore.yaml")
+	assert.NoError(t, err)
+
+	endpoints, err := Import(doc)
+	assert.NoError(t, err)
+
+	got := map[string]store.Response{}
+	for _, e := range endpoints {
+		assert.Equal(t, "endpoints", e.Type)
+		got[e.Attributes.Verb+" "+e.Attributes.Path] = e.Attributes.Response
+	}
+	assert.Len(t, got, 5)
+
+	t.Run("text example", func(t *testing.T) {
+		assert.Equal(t, store.Response{Code: 200, Headers: store.Headers{"Content-Type": {"text/plain"}}, Body: "OK"}, got["GET /health"])
+	})
+
+	t.Run("referenced response example", func(t *testing.T) {
+		assert.Equal(t, 200, got["GET /pets/{petId}"].Code)
+		assert.JSONEq(t, `{"id":7,"name":"Fido"}`, string(got["GET /pets/{petId}"].JSON))
+	})
+
+	t.Run("first named example of the first success code", func(t *testing.T) {
+		assert.Equal(t, 201, got["POST /pets"].Code)
+		assert.Equal(t, "application/json", got["POST /pets"].Headers.Get("Content-Type"))
+		assert.JSONEq(t, `{"id":1,"name":"Rex"}`, string(got["POST /pets"].JSON))
+	})
+
+	t.Run("responses without content", func(t *testing.T) {
+		assert.Equal(t, store.Response{Code: 204, Headers: store.Headers{}}, got["DELETE /pets/{petId}"])
+	})
+
+	t.Run("body generated from a recursive schema", func(t *testing.T) {
+		var pets []map[string]any
+		assert.NoError(t, json.Unmarshal(got["GET /pets"].JSON, &pets))
+		assert.Len(t, pets, 1)
+		assert.Equal(t, 0.0, pets[0]["id"])
+		assert.Equal(t, "Rex", pets[0]["name"])
+		assert.Equal(t, "dog", pets[0]["tag"])
+		assert.Equal(t, "2024-01-01", pets[0]["born"])
+		owner := pets[0]["owner"].(map[string]any)
+		assert.Equal(t, "user@example.com", owner["email"])
+		assert.IsType(t, []any{}, owner["pets"])
+	})
+}
+
+func TestImportErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"invalid document": "openapi: [",
+		"swagger 2":        `{"swagger": "2.0", "paths": {}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Import([]byte(doc))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("JSON documents", func(t *testing.T) {
+		endpoints, err := Import([]byte(`{"openapi":"3.1.0","paths":{"/ping":{"head":{"responses":{"200":{"description":"ok"}}}}}}`))
+		assert.NoError(t, err)
+		assert.Len(t, endpoints, 1)
+		assert.Equal(t, "HEAD", endpoints[0].Attributes.Verb)
+	})
+}
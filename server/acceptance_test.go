@@ -13,7 +13,7 @@ import (
 )
 
 func TestAcceptance(t *testing.T) {
-	store, err := store.New()
+	store, err := store.New("")
 	assert.NoError(t, err)
 	defer store.Close()
 
@@ -43,7 +43,7 @@ func TestAcceptance(t *testing.T) {
 			reqMethod:      http.MethodPost,
 			reqBody:        `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/hello","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"\"{ \"message\": \"Hello, world\" }\""}}}}`,
 			wantResCode:    http.StatusCreated,
-			wantResBody:    `{"data":{"type":"endpoints","id":1,"attributes":{"verb":"GET","path":"/hello","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"\"{ \"message\": \"Hello, world\" }\""}}}}`,
+			wantResBody:    `{"data":{"type":"endpoints","id":1,"attributes":{"verb":"GET","path":"/hello","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"","json":{"message":"Hello, world"}}}}}`,
 			wantResHeaders: map[string]string{"Content-Type": "application/vnd.api+json"},
 		},
 		{
@@ -59,7 +59,7 @@ func TestAcceptance(t *testing.T) {
 			reqPath:        "/hello",
 			reqMethod:      http.MethodPost,
 			wantResCode:    http.StatusNotFound,
-			wantResBody:    "{\"errors\":[{\"code\":\"Not Found\", \"detail\":\"Requested page `/hello` does not exist\"}]}",
+			wantResBody:    "{\"errors\":[{\"code\":\"Not Found\", \"detail\":\"Requested page `/hello` does not exist\", \"meta\":{\"nearMisses\":[{\"id\":1,\"verb\":\"GET\",\"path\":\"/hello\",\"reasons\":[\"verb is POST, expected GET\"]}]}}]}",
 			wantResHeaders: map[string]string{"Content-Type": "application/vnd.api+json"},
 		},
 	}
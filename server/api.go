@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// WithAPIPrefix serves the API under prefix, like /__echo, so that the paths
+// of the API can be mocked too. The API is served at the root by default.
+func WithAPIPrefix(prefix string) Option {
+	return func(h *handlers) {
+		h.apiPrefix = CleanAPIPrefix(prefix)
+	}
+}
+
+// CleanAPIPrefix returns prefix with a leading slash and without a trailing
+// one, or empty for the root.
+func CleanAPIPrefix(prefix string) string {
+	if prefix = strings.Trim(prefix, "/"); prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// NewSplit returns the server of the mock endpoints and the handler of the
+// API, to serve them on different listeners. None of the mock paths are
+// reserved for the API then.
+func NewSplit(s store.Storage, opts ...Option) (mocks *Server, api http.Handler) {
+	handle := newHandlers(s, opts...)
+	handle.split = true
+	handle.api.HandleFunc("/", apiNotFound)
+	api = withNamespaceMiddleware(handle.api)
+	if handle.apiPrefix != "" {
+		api = http.StripPrefix(handle.apiPrefix, api)
+	}
+	handle.start()
+	return &Server{Handler: withVndHeaderMiddleware(withNamespaceMiddleware(handle.all())), handlers: handle},
+		withVndHeaderMiddleware(api)
+}
+
+func apiNotFound(w http.ResponseWriter, r *http.Request) {
+	replyWithErr(w, http.StatusNotFound, fmt.Sprintf("there's no API route `%s %s`", r.Method, r.URL.Path))
+}
+
+// checkReserved returns an error when the requests to the endpoint would be
+// served by the API, or have its path taken as a namespace, instead.
+func (h *handlers) checkReserved(e *store.Endpoint) error {
+	a := e.Attributes
+	if strings.HasPrefix(a.Path, namespacePrefix) {
+		return fmt.Errorf("path `%s` is reserved for namespaces", a.Path)
+	}
+	switch {
+	case h.split:
+		return nil
+	case h.apiPrefix != "":
+		if a.Path == h.apiPrefix || strings.HasPrefix(a.Path, h.apiPrefix+"/") {
+			return fmt.Errorf("path `%s` is reserved for the API", a.Path)
+		}
+	default:
+		_, pattern := h.api.Handler(&http.Request{Method: a.Verb, URL: &url.URL{Path: a.Path}})
+		if pattern != "" && pattern != "/" {
+			return fmt.Errorf("route `%s %s` is reserved for the API", a.Verb, a.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func endpointJSON(verb, path string) string {
+	return `{"data":{"type":"endpoints","attributes":{"verb":"` + verb + `","path":"` + path + `","response":{"code":200,"body":"mocked"}}}}`
+}
+
+func TestReservedRoutes(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	tests := []struct {
+		verb string
+		path string
+		want int
+	}{
+		{verb: "GET", path: "/endpoints", want: http.StatusBadRequest},
+		{verb: "PATCH", path: "/endpoints/{id}", want: http.StatusBadRequest},
+		{verb: "DELETE", path: "/endpoints/7", want: http.StatusBadRequest},
+		{verb: "GET", path: "/endpoints/{id}", want: http.StatusCreated},
+		{verb: "POST", path: "/requests/count", want: http.StatusBadRequest},
+		{verb: "GET", path: "/requests/count", want: http.StatusCreated},
+		{verb: "PUT", path: "/proxy", want: http.StatusBadRequest},
+		{verb: "GET", path: "/ns/ci-1/hello", want: http.StatusBadRequest},
+		{verb: "GET", path: "/nsfw", want: http.StatusCreated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.verb+" "+test.path, func(t *testing.T) {
+			res, body := do(t, http.MethodPost, server.URL+"/endpoints", endpointJSON(test.verb, test.path))
+			assert.Equal(t, test.want, res.StatusCode, body)
+		})
+	}
+
+	t.Run("imports skip reserved routes", func(t *testing.T) {
+		bundle := `{"meta":{"format":"echo","version":2},"data":[` +
+			`{"type":"endpoints","attributes":{"verb":"GET","path":"/scenarios","response":{"code":200}}},` +
+			`{"type":"endpoints","attributes":{"verb":"GET","path":"/imported","response":{"code":200}}}]}`
+		res, body := do(t, http.MethodPost, server.URL+"/endpoints/import?format=echo", bundle)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Contains(t, body, `"skipped":["GET /scenarios: route `+"`GET /scenarios`"+` is reserved for the API"]`)
+		assert.Contains(t, body, `"path":"/imported"`)
+	})
+}
+
+func TestAPIPrefix(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory(), WithAPIPrefix("__echo/")))
+	defer server.Close()
+	api := server.URL + "/__echo"
+
+	mustCreateEndpoint(t, api, endpointJSON("GET", "/endpoints"))
+	mustCreateEndpoint(t, api+"/ns/ci-1", endpointJSON("POST", "/requests/count"))
+
+	res, body := do(t, http.MethodGet, server.URL+"/endpoints", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "mocked", body)
+	res, body = do(t, http.MethodPost, server.URL+"/ns/ci-1/requests/count", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "mocked", body)
+
+	_, body = do(t, http.MethodGet, api+"/endpoints", "")
+	assert.Contains(t, body, `"path":"/endpoints"`)
+	_, body = do(t, http.MethodGet, api+"/ns/ci-1/requests", "")
+	assert.Contains(t, body, `"path":"/requests/count"`)
+
+	res, body = do(t, http.MethodGet, api+"/nothing", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.JSONEq(t, `{"errors":[{"code":"Not Found","detail":"there's no API route `+"`GET /nothing`"+`"}]}`, body)
+
+	res, _ = do(t, http.MethodPost, api+"/endpoints", endpointJSON("GET", "/__echo/endpoints"))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res, _ = do(t, http.MethodPost, api+"/endpoints", endpointJSON("GET", "/__echoes"))
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+}
+
+func TestNewSplit(t *testing.T) {
+	mocks, api := NewSplit(store.NewMemory())
+	mockServer := httptest.NewServer(mocks)
+	defer mockServer.Close()
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	mustCreateEndpoint(t, apiServer.URL, endpointJSON("POST", "/endpoints"))
+
+	res, body := do(t, http.MethodPost, mockServer.URL+"/endpoints", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "mocked", body)
+	res, _ = do(t, http.MethodGet, mockServer.URL+"/requests", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	_, body = do(t, http.MethodGet, apiServer.URL+"/requests", "")
+	assert.Equal(t, 2, strings.Count(body, `"type":"requests"`))
+	res, _ = do(t, http.MethodGet, apiServer.URL+"/hello", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader carries the static API keys accepted by the Endpoints API.
+const APIKeyHeader = "X-API-Key"
+
+// AuthConfig lists the credentials accepted by the Endpoints API, and what
+// they grant. The API is open when none are set. Mock endpoints are always
+// open.
+type AuthConfig struct {
+	// APIKeys maps the static keys sent in the X-API-Key header to their
+	// grants.
+	APIKeys map[string]Grant
+	// Users maps the usernames of HTTP Basic authentication to their
+	// passwords and grants.
+	Users map[string]User
+	// JWTSecret is the HMAC secret Bearer tokens are signed with, using HS256,
+	// HS384 or HS512. Their grant is read from the `role` and `namespaces`
+	// claims, and tokens without a role are rejected.
+	JWTSecret string
+	// JWTScope is a scope Bearer tokens must grant in their `scope` claim, if
+	// any. Tokens without it are forbidden.
+	JWTScope string
+}
+
+// User is an HTTP Basic user.
+type User struct {
+	Password string
+	Grant    Grant
+}
+
+func (c AuthConfig) enabled() bool {
+	return len(c.APIKeys) > 0 || len(c.Users) > 0 || c.JWTSecret != ""
+}
+
+// WithAuth requires the credentials in c on the Endpoints API.
+func WithAuth(c AuthConfig) Option {
+	return func(h *handlers) {
+		h.auth = c
+	}
+}
+
+// errForbidden is returned for valid credentials that don't grant access.
+var errForbidden = errors.New("the credentials don't grant access to the Endpoints API")
+
+// authenticate rejects requests to next without valid credentials with 401
+// Unauthorized, and those whose credentials don't grant access with 403
+// Forbidden. The grant of the credentials is added to the request context.
+func (h *handlers) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	if !h.auth.enabled() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, err := h.auth.check(r, time.Now())
+		switch {
+		case errors.Is(err, errForbidden):
+			replyWithErr(w, http.StatusForbidden, err.Error())
+		case err != nil:
+			w.Header().Set("WWW-Authenticate", h.auth.challenge())
+			replyWithErr(w, http.StatusUnauthorized, err.Error())
+		default:
+			next(w, r.WithContext(context.WithValue(r.Context(), grantKey{}, g)))
+		}
+	}
+}
+
+func (c AuthConfig) check(r *http.Request, now time.Time) (Grant, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		for k, g := range c.APIKeys {
+			if equal(k, key) {
+				return g, nil
+			}
+		}
+		return Grant{}, errors.New("invalid API key")
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		if u, found := c.Users[user]; found && equal(u.Password, password) {
+			return u.Grant, nil
+		}
+		return Grant{}, errors.New("invalid username or password")
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && c.JWTSecret != "" {
+		claims, err := verifyJWT(token, []byte(c.JWTSecret), now)
+		if err != nil {
+			return Grant{}, fmt.Errorf("invalid token: %v", err)
+		}
+		if c.JWTScope != "" && !slices.Contains(strings.Fields(claims.Scope), c.JWTScope) {
+			return Grant{}, errForbidden
+		}
+		// Unlike configured credentials, tokens don't default to admin: a
+		// token that forgets its role grants nothing.
+		if claims.Role == 0 {
+			return Grant{}, errors.New("invalid token: missing the role claim")
+		}
+		return claims.Grant, nil
+	}
+	return Grant{}, errors.New("missing credentials")
+}
+
+// challenge is the WWW-Authenticate header of 401 responses.
+func (c AuthConfig) challenge() string {
+	var schemes []string
+	if len(c.Users) > 0 {
+		schemes = append(schemes, `Basic realm="echo"`)
+	}
+	if c.JWTSecret != "" {
+		schemes = append(schemes, `Bearer realm="echo"`)
+	}
+	if len(c.APIKeys) > 0 {
+		schemes = append(schemes, `APIKey realm="echo"`)
+	}
+	return strings.Join(schemes, ", ")
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// claims are the registered JWT claims checked by verifyJWT, along with the
+// scope and the grant of the token.
+type claims struct {
+	ExpiresAt *int64 `json:"exp"`
+	NotBefore *int64 `json:"nbf"`
+	Scope     string `json:"scope"`
+	Grant
+}
+
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// verifyJWT checks the signature and the validity period of an HMAC-signed
+// JWT and returns its claims.
+func verifyJWT(token string, secret []byte, now time.Time) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+	}{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	alg, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	mac := hmac.New(alg, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.New("signature mismatch")
+	}
+	c := &claims{}
+	if err := decodeJWTPart(parts[1], c); err != nil {
+		return nil, err
+	}
+	if c.ExpiresAt != nil && !now.Before(time.Unix(*c.ExpiresAt, 0)) {
+		return nil, errors.New("token expired")
+	}
+	if c.NotBefore != nil && now.Before(time.Unix(*c.NotBefore, 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	return c, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
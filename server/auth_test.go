@@ -0,0 +1,170 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+const jwtSecret = "s3cr3t"
+
+func TestAuth(t *testing.T) {
+	s := store.NewMemory()
+	assert.NoError(t, s.Seed())
+	server := httptest.NewServer(New(s, WithAuth(AuthConfig{
+		APIKeys:   map[string]Grant{"k3y": {Role: Admin}},
+		Users:     map[string]User{"alice": {Password: "pa55"}},
+		JWTSecret: jwtSecret,
+		JWTScope:  "echo",
+	})))
+	defer server.Close()
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		authorize func(*http.Request)
+		want      int
+	}{
+		{
+			name:      "no credentials",
+			authorize: func(*http.Request) {},
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "API key",
+			authorize: func(r *http.Request) { r.Header.Set(APIKeyHeader, "k3y") },
+			want:      http.StatusOK,
+		},
+		{
+			name:      "wrong API key",
+			authorize: func(r *http.Request) { r.Header.Set(APIKeyHeader, "nope") },
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "basic auth",
+			authorize: func(r *http.Request) { r.SetBasicAuth("alice", "pa55") },
+			want:      http.StatusOK,
+		},
+		{
+			name:      "wrong password",
+			authorize: func(r *http.Request) { r.SetBasicAuth("alice", "nope") },
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "unknown user",
+			authorize: func(r *http.Request) { r.SetBasicAuth("bob", "pa55") },
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "token",
+			authorize: bearer(signJWT(t, "HS256", jwtSecret, map[string]any{"sub": "ci", "role": "viewer", "scope": "read echo", "exp": now.Add(time.Minute).Unix()})),
+			want:      http.StatusOK,
+		},
+		{
+			name:      "token without a role",
+			authorize: bearer(signJWT(t, "HS256", jwtSecret, map[string]any{"sub": "ci", "scope": "echo"})),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "token without the scope",
+			authorize: bearer(signJWT(t, "HS256", jwtSecret, map[string]any{"sub": "ci", "scope": "read"})),
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "expired token",
+			authorize: bearer(signJWT(t, "HS256", jwtSecret, map[string]any{"scope": "echo", "exp": now.Add(-time.Minute).Unix()})),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "token not valid yet",
+			authorize: bearer(signJWT(t, "HS256", jwtSecret, map[string]any{"scope": "echo", "nbf": now.Add(time.Minute).Unix()})),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "token signed with another secret",
+			authorize: bearer(signJWT(t, "HS256", "other", map[string]any{"scope": "echo"})),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "unsigned token",
+			authorize: bearer(signJWT(t, "none", jwtSecret, map[string]any{"scope": "echo"})),
+			want:      http.StatusUnauthorized,
+		},
+		{
+			name:      "malformed token",
+			authorize: bearer("not.a.token"),
+			want:      http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/endpoints", nil)
+			assert.NoError(t, err)
+			test.authorize(req)
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, test.want, res.StatusCode)
+			assert.Equal(t, "application/vnd.api+json", res.Header.Get("Content-Type"))
+			if test.want == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="echo", Bearer realm="echo", APIKey realm="echo"`, res.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("every API route is protected", func(t *testing.T) {
+		for _, route := range []string{
+			getEndpointsPath, postEndpoinstPath, patchEndpointsPath, deleteEndpointsPath, importEndpointsPath, exportEndpointsPath,
+			getRequestsPath, countRequestsPath, deleteRequestsPath, getProxyPath, putProxyPath,
+			getScenariosPath, putScenarioPath, deleteScenariosPath, getNamespacesPath, postNamespacesPath, deleteNamespacePath,
+			getMockFilesPath, getCAPath,
+		} {
+			method, path, _ := strings.Cut(route, " ")
+			res, body := do(t, method, server.URL+strings.NewReplacer("{id}", "1", "{name}", "a").Replace(path), "")
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, route)
+			assert.JSONEq(t, `{"errors":[{"code":"Unauthorized","detail":"missing credentials"}]}`, body, route)
+		}
+	})
+
+	t.Run("mock endpoints are open", func(t *testing.T) {
+		res, _ := do(t, http.MethodGet, server.URL+"/revert_entropy", "")
+		assert.NotEqual(t, http.StatusUnauthorized, res.StatusCode)
+		assert.NotEqual(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestAuthDisabled(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	res, _ := do(t, http.MethodGet, server.URL+"/endpoints", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func bearer(token string) func(*http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func signJWT(t testing.TB, alg, secret string, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// WithFixtures sets the directory response files are read from. Endpoints
+// can't serve files when it's not set.
+func WithFixtures(dir string) Option {
+	return func(h *handlers) {
+		h.fixtures = dir
+	}
+}
+
+// body returns the bytes served as the body of the response.
+func (h *handlers) body(r *store.Response) ([]byte, error) {
+	switch {
+	case r.File != "":
+		return os.ReadFile(h.fixturePath(r.File))
+	case len(r.JSON) > 0:
+		return r.JSON, nil
+	case r.Encoding == store.Base64Encoding:
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+func (h *handlers) fixturePath(file string) string {
+	return filepath.Join(h.fixtures, filepath.FromSlash(file))
+}
+
+// checkFixtures verifies that the files served by the endpoint exist.
+func (h *handlers) checkFixtures(e *store.Endpoint) error {
+	for _, r := range e.Attributes.Responses() {
+		if r.File == "" {
+			continue
+		}
+		if h.fixtures == "" {
+			return errors.New("unable to serve files: there's no fixtures directory")
+		}
+		if info, err := os.Stat(h.fixturePath(r.File)); err != nil || info.IsDir() {
+			return fmt.Errorf("fixture `%s` does not exist", r.File)
+		}
+	}
+	return nil
+}
+
+// contentType guesses the content type of the body from the file extension,
+// the encoding or the body itself. Bodies without an encoding are left for
+// net/http to sniff, as they always were.
+func contentType(r *store.Response, body []byte) string {
+	switch {
+	case r.File != "":
+		if t := mime.TypeByExtension(path.Ext(r.File)); t != "" {
+			return t
+		}
+	case len(r.JSON) > 0, r.Encoding == store.JSONEncoding:
+		return "application/json"
+	case r.Encoding == "":
+		return ""
+	}
+	return http.DetectContentType(body)
+}
+
+// writeHeader writes the status code, headers and cookies of the response,
+// filling in the Content-Type and Content-Length of the body.
+func writeHeader(w http.ResponseWriter, r *store.Response, body []byte) {
+	// Remove application/vnd.api+json passed by middleware.
+	w.Header().Del("Content-Type")
+
+	for k, values := range r.Headers {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	for _, c := range r.Cookies {
+		http.SetCookie(w, c.HTTP())
+	}
+	if w.Header().Get("Content-Type") == "" && len(body) > 0 {
+		if t := contentType(r, body); t != "" {
+			w.Header().Set("Content-Type", t)
+		}
+	}
+	if bodyAllowed(r.Code) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(r.Code)
+}
+
+// bodyAllowed reports whether responses with the status code can have a body.
+func bodyAllowed(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
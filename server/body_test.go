@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseBodies(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	fixtures := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(fixtures, "docs"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(fixtures, "docs", "report.pdf"), []byte("%PDF-1.4 binary \x00\xff"), 0o644))
+
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s, WithFixtures(fixtures)))
+	defer server.Close()
+
+	endpoint := `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/%s","response":{"code":200,%s}}}}`
+	for path, res := range map[string]string{
+		"base64":  fmt.Sprintf(`"encoding":"base64","body":"%s"`, base64.StdEncoding.EncodeToString(png)),
+		"json":    `"encoding":"json","body":"{\"ok\":true}"`,
+		"text":    `"encoding":"text","body":"\"quoted\""`,
+		"plain":   `"body":"\"quoted\""`,
+		"value":   `"json":{"ok": [1, 2]}`,
+		"quoted":  `"body":"\"{ \"ok\": true }\""`,
+		"literal": `"encoding":"text","body":"\"{ \"ok\": true }\""`,
+		"file":    `"file":"docs/report.pdf","headers":{"Cache-Control":"no-cache"}`,
+		"typed":   `"encoding":"base64","body":"AAEC","headers":{"Content-Type":"application/x-protobuf"}`,
+	} {
+		mustCreateEndpoint(t, server.URL, fmt.Sprintf(endpoint, path, res))
+	}
+
+	tests := []struct {
+		path            string
+		wantBody        string
+		wantContentType string
+	}{
+		{path: "/base64", wantBody: string(png), wantContentType: "image/png"},
+		{path: "/json", wantBody: `{"ok":true}`, wantContentType: "application/json"},
+		{path: "/text", wantBody: `"quoted"`, wantContentType: "text/plain; charset=utf-8"},
+		{path: "/plain", wantBody: `"quoted"`, wantContentType: "text/plain; charset=utf-8"},
+		{path: "/value", wantBody: `{"ok":[1,2]}`, wantContentType: "application/json"},
+		{path: "/quoted", wantBody: `{"ok":true}`, wantContentType: "application/json"},
+		{path: "/literal", wantBody: `"{ "ok": true }"`, wantContentType: "text/plain; charset=utf-8"},
+		{path: "/file", wantBody: "%PDF-1.4 binary \x00\xff", wantContentType: "application/pdf"},
+		{path: "/typed", wantBody: "\x00\x01\x02", wantContentType: "application/x-protobuf"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			res, body := do(t, http.MethodGet, server.URL+test.path, "")
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, test.wantBody, body)
+			assert.Equal(t, test.wantContentType, res.Header.Get("Content-Type"))
+			assert.Equal(t, int64(len(test.wantBody)), res.ContentLength)
+		})
+	}
+
+	t.Run("files that don't exist or are outside the fixtures are rejected", func(t *testing.T) {
+		for _, file := range []string{"missing.png", "../secret", "/etc/passwd", "docs"} {
+			res, _ := do(t, http.MethodPost, server.URL+"/endpoints", fmt.Sprintf(endpoint, "bad", fmt.Sprintf(`"file":%q`, file)))
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, file)
+		}
+	})
+
+	t.Run("files can't be served without fixtures", func(t *testing.T) {
+		server := httptest.NewServer(New(store.NewMemory()))
+		defer server.Close()
+		res, _ := do(t, http.MethodPost, server.URL+"/endpoints", fmt.Sprintf(endpoint, "file", `"file":"docs/report.pdf"`))
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestResponseHeaders(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/login","response":{"code":200,"template":true,
+		"headers":{"Vary":["Accept","Origin"],"Set-Cookie":"legacy=1","X-Request":"{{index .Headers \"X-Id\"}}"},
+		"cookies":[
+			{"name":"session","value":"{{index .Headers \"X-Id\"}}","path":"/","httpOnly":true,"sameSite":"Strict"},
+			{"name":"theme","value":"dark","domain":"example.com","expires":"2030-01-01T00:00:00Z","secure":true}
+		]}}}}`)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/login", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Id", "abc")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, []string{"Accept", "Origin"}, res.Header.Values("Vary"))
+	assert.Equal(t, "abc", res.Header.Get("X-Request"))
+	assert.Equal(t, []string{
+		"legacy=1",
+		"session=abc; Path=/; HttpOnly; SameSite=Strict",
+		"theme=dark; Domain=example.com; Expires=Tue, 01 Jan 2030 00:00:00 GMT; Secure",
+	}, res.Header.Values("Set-Cookie"))
+
+	t.Run("headers are listed as strings or lists", func(t *testing.T) {
+		_, body := do(t, http.MethodGet, server.URL+"/endpoints", "")
+		assert.Contains(t, body, `"headers":{"Set-Cookie":"legacy=1","Vary":["Accept","Origin"],"X-Request":"{{index .Headers \"X-Id\"}}"}`)
+	})
+
+	t.Run("invalid cookies are rejected", func(t *testing.T) {
+		res, _ := do(t, http.MethodPost, server.URL+"/endpoints", `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/bad","response":{"code":200,"cookies":[{"name":"a b"}]}}}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/har"
+	"github.com/Alvaroalonsobabbel/echo/openapi"
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// bundleVersion is the version of the native export format. Bundles with a
+// newer version are rejected on import. Version 2 serves bodies as they are,
+// with JSON documents in their own field.
+const bundleVersion = 2
+
+// bundle is the native export format. It holds every endpoint as it's stored,
+// so importing it into a fresh store reproduces the exported one.
+type bundle struct {
+	Meta struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	} `json:"meta"`
+	Data []*store.Endpoint `json:"data"`
+}
+
+// exportEndpoints writes the endpoints in the format requested by `?format=`:
+// an OpenAPI document, a HAR file or, by default, a native bundle.
+func (h *handlers) exportEndpoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := h.endpoints(namespace(r))
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to fetch endpoints: %v", err))
+			return
+		}
+		var (
+			doc         []byte
+			contentType = "application/json"
+		)
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "echo":
+			b := &bundle{Data: endpoints}
+			b.Meta.Format = "echo"
+			b.Meta.Version = bundleVersion
+			doc, err = json.Marshal(b)
+			contentType = "application/vnd.api+json"
+		case "openapi":
+			doc, err = openapi.Export(endpoints)
+		case "har":
+			doc, err = har.Export(endpoints, baseURL(r), time.Now().UTC())
+		default:
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unsupported export format `%s`", format))
+			return
+		}
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error exporting endpoints: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(doc) //nolint:errcheck // nothing to do if the client went away
+	}
+}
+
+// ReadBundle reads the endpoints of a native bundle, as exported by
+// GET /endpoints/export.
+func ReadBundle(doc []byte) ([]*store.Endpoint, error) {
+	b := &bundle{}
+	if err := json.Unmarshal(doc, b); err != nil {
+		return nil, fmt.Errorf("Unable to decode bundle: %v", err)
+	}
+	if b.Meta.Format != "echo" || b.Meta.Version < 1 || b.Meta.Version > bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle `%s` version %d", b.Meta.Format, b.Meta.Version)
+	}
+	if b.Meta.Version == 1 {
+		for _, e := range b.Data {
+			store.UnquoteBodies(e)
+		}
+	}
+	return b.Data, nil
+}
+
+// baseURL returns the URL the endpoints of the request's namespace are served
+// from.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if ns := namespace(r); ns != "" {
+		return scheme + "://" + r.Host + namespacePrefix + ns
+	}
+	return scheme + "://" + r.Host
+}
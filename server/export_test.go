@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportEndpoints(t *testing.T) {
+	s := store.NewMemory()
+	assert.NoError(t, s.Seed())
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/users/{id}","match":{"headers":{"X-Tenant":"acme"}},"priority":2,"response":{"code":200,"body":"{{.Path}}","template":true}}}}`)
+
+	t.Run("native bundles round trip into a fresh store", func(t *testing.T) {
+		res, doc := do(t, http.MethodGet, server.URL+"/endpoints/export?format=echo", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/vnd.api+json", res.Header.Get("Content-Type"))
+
+		fresh := store.NewMemory()
+		other := httptest.NewServer(New(fresh))
+		defer other.Close()
+		res, body := do(t, http.MethodPost, other.URL+"/endpoints/import?format=echo", doc)
+		assert.Equal(t, http.StatusCreated, res.StatusCode, body)
+
+		want, err := s.FetchEndpoints()
+		assert.NoError(t, err)
+		got, err := fresh.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("OpenAPI", func(t *testing.T) {
+		res, doc := do(t, http.MethodGet, server.URL+"/endpoints/export?format=openapi", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		got := map[string]any{}
+		assert.NoError(t, json.Unmarshal([]byte(doc), &got))
+		assert.Equal(t, "3.0.3", got["openapi"])
+		assert.Len(t, got["paths"], 5)
+	})
+
+	t.Run("HAR", func(t *testing.T) {
+		res, doc := do(t, http.MethodGet, server.URL+"/endpoints/export?format=har", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		got := struct {
+			Log struct {
+				Entries []struct {
+					Request struct {
+						URL string `json:"url"`
+					} `json:"request"`
+				} `json:"entries"`
+			} `json:"log"`
+		}{}
+		assert.NoError(t, json.Unmarshal([]byte(doc), &got))
+		assert.Len(t, got.Log.Entries, 5)
+		assert.Equal(t, server.URL+"/revert_entropy", got.Log.Entries[0].Request.URL)
+	})
+
+	t.Run("version 1 bundles keep serving the same bodies", func(t *testing.T) {
+		other := httptest.NewServer(New(store.NewMemory()))
+		defer other.Close()
+		res, body := do(t, http.MethodPost, other.URL+"/endpoints/import?format=echo", `{"meta":{"format":"echo","version":1},"data":[
+			{"type":"endpoints","attributes":{"verb":"GET","path":"/text","response":{"code":200,"body":"\"hi\""}}},
+			{"type":"endpoints","attributes":{"verb":"GET","path":"/json","response":{"code":200,"body":"\"{\"a\": 1}\""}}}
+		]}`)
+		assert.Equal(t, http.StatusCreated, res.StatusCode, body)
+
+		_, body = do(t, http.MethodGet, other.URL+"/text", "")
+		assert.Equal(t, "hi", body)
+		res, body = do(t, http.MethodGet, other.URL+"/json", "")
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.Equal(t, `{"a":1}`, body)
+	})
+
+	t.Run("invalid formats and bundles return 400", func(t *testing.T) {
+		res, _ := do(t, http.MethodGet, server.URL+"/endpoints/export?format=raml", "")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res, _ = do(t, http.MethodPost, server.URL+"/endpoints/import?format=echo", `{"meta":{"format":"echo","version":99},"data":[]}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res, _ = do(t, http.MethodPost, server.URL+"/endpoints/import?format=echo", `not json`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
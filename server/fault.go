@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// throttleInterval is how often throttled responses send a chunk of bytes.
+const throttleInterval = 100 * time.Millisecond
+
+// sampleDelay returns how long to wait before replying.
+func sampleDelay(d *store.Delay) time.Duration {
+	var ms float64
+	switch d.Distribution {
+	case store.Fixed:
+		ms = float64(d.Milliseconds)
+	case store.Uniform:
+		ms = float64(d.Min + rand.IntN(d.Max-d.Min+1))
+	case store.Normal:
+		ms = float64(d.Mean) + float64(d.StdDev)*rand.NormFloat64()
+	case store.LogNormal:
+		ms = float64(d.Median) * math.Exp(d.Sigma*rand.NormFloat64())
+	}
+	return time.Duration(max(ms, 0) * float64(time.Millisecond))
+}
+
+// wait sleeps for the delay, unless the client goes away first.
+func wait(ctx context.Context, delay time.Duration) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// fails reports whether the request should fail with the fault.
+func fails(f *store.Fault) bool {
+	return f.Probability == nil || rand.Float64() < *f.Probability
+}
+
+// serveFault replies to the request with the given fault instead of the
+// response, or with part of it.
+func serveFault(w http.ResponseWriter, res *store.Response, body []byte, f *store.Fault) {
+	switch f.Type {
+	case store.ErrorFault:
+		code := f.Code
+		if code == 0 {
+			code = http.StatusInternalServerError
+		}
+		serve(w, &store.Response{Code: code}, nil)
+	case store.CloseFault:
+		resetConnection(w)
+	case store.EmptyFault:
+		// Aborting the handler closes the connection without replying.
+		panic(http.ErrAbortHandler)
+	case store.TruncateFault:
+		writeHeader(w, res, body)
+		w.Write(body[:len(body)/2])           //nolint:errcheck // the connection is closed anyway
+		http.NewResponseController(w).Flush() //nolint:errcheck // the connection is closed anyway
+		panic(http.ErrAbortHandler)
+	case store.ThrottleFault:
+		serveThrottled(w, res, body, f.BytesPerSecond)
+	}
+}
+
+// resetConnection closes the connection so that the client gets a reset
+// instead of a clean close. It falls back to aborting the handler when the
+// connection can't be taken over.
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0) //nolint:errcheck // the connection is closed right after
+	}
+	conn.Close()
+}
+
+// serveThrottled writes the response body in chunks, so that it takes as long
+// as it would at the given bandwidth.
+func serveThrottled(w http.ResponseWriter, res *store.Response, body []byte, bytesPerSecond int) {
+	writeHeader(w, res, body)
+
+	rc := http.NewResponseController(w)
+	chunk := max(bytesPerSecond*int(throttleInterval)/int(time.Second), 1)
+	ticker := time.NewTicker(throttleInterval)
+	defer ticker.Stop()
+	for len(body) > 0 {
+		n := min(chunk, len(body))
+		if _, err := w.Write(body[:n]); err != nil {
+			slog.Warn("error writing throttled response", "err", err)
+			return
+		}
+		rc.Flush() //nolint:errcheck // nothing to do if the client went away
+		if body = body[n:]; len(body) > 0 {
+			<-ticker.C
+		}
+	}
+}
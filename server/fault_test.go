@@ -0,0 +1,97 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    *store.Delay
+		min, max time.Duration
+	}{
+		{name: "fixed", delay: &store.Delay{Distribution: store.Fixed, Milliseconds: 20}, min: 20 * time.Millisecond, max: 20 * time.Millisecond},
+		{name: "uniform", delay: &store.Delay{Distribution: store.Uniform, Min: 10, Max: 30}, min: 10 * time.Millisecond, max: 30 * time.Millisecond},
+		{name: "normal is never negative", delay: &store.Delay{Distribution: store.Normal, Mean: 1, StdDev: 100}, min: 0, max: time.Hour},
+		{name: "lognormal", delay: &store.Delay{Distribution: store.LogNormal, Median: 10, Sigma: 0.5}, min: time.Nanosecond, max: time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for range 100 {
+				d := sampleDelay(test.delay)
+				assert.GreaterOrEqual(t, d, test.min)
+				assert.LessOrEqual(t, d, test.max)
+			}
+		})
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+
+	endpoint := `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/%s","response":{"code":200,"body":"0123456789abcdefghijklmnopqrst"},%s}}}`
+	for path, attrs := range map[string]string{
+		"slow":     `"delay":{"distribution":"fixed","milliseconds":100}`,
+		"error":    `"fault":{"type":"error","code":503}`,
+		"never":    `"fault":{"type":"error","probability":0}`,
+		"close":    `"fault":{"type":"close"}`,
+		"empty":    `"fault":{"type":"empty"}`,
+		"truncate": `"fault":{"type":"truncate"}`,
+		"throttle": `"fault":{"type":"throttle","bytesPerSecond":100}`,
+	} {
+		mustCreateEndpoint(t, server.URL, fmt.Sprintf(endpoint, path, attrs))
+	}
+
+	t.Run("delay", func(t *testing.T) {
+		start := time.Now()
+		res, _ := do(t, http.MethodGet, server.URL+"/slow", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		res, body := do(t, http.MethodGet, server.URL+"/error", "")
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Empty(t, body)
+	})
+
+	t.Run("probability", func(t *testing.T) {
+		res, _ := do(t, http.MethodGet, server.URL+"/never", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	for _, path := range []string{"close", "empty"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := http.Get(server.URL + "/" + path)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/truncate")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "0123456789abcde", string(body))
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		start := time.Now()
+		res, body := do(t, http.MethodGet, server.URL+"/throttle", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "0123456789abcdefghijklmnopqrst", body)
+		// 30 bytes at 100 bytes per second are sent in 3 chunks of 10.
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// EnableHTTP2 makes srv serve HTTP/2, negotiated with ALPN over TLS and as
+// cleartext h2c on plain connections, where clients either start with the
+// HTTP/2 preface (prior knowledge) or upgrade an HTTP/1.1 request. It must be
+// called once the handler, the timeouts and the TLS config of srv are set.
+func EnableHTTP2(srv *http.Server) error {
+	h2 := &http2.Server{IdleTimeout: srv.IdleTimeout}
+	plain := srv.TLSConfig == nil
+	// ConfigureServer also lets Shutdown close the idle HTTP/2 connections,
+	// h2c ones included, which the server doesn't track once upgraded.
+	if err := http2.ConfigureServer(srv, h2); err != nil {
+		return err
+	}
+	if plain {
+		// ConfigureServer sets a TLS config, which would make Serve use TLS.
+		srv.TLSConfig = nil
+		srv.Handler = h2c.NewHandler(srv.Handler, h2)
+	}
+	return nil
+}
+
+// DisableHTTP2 makes srv serve HTTP/1.1 only, even to TLS clients that
+// support HTTP/2.
+func DisableHTTP2(srv *http.Server) {
+	srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+}
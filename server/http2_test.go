@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/certs"
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestHTTP2(t *testing.T) {
+	t.Run("h2c", func(t *testing.T) {
+		srv := &http.Server{Addr: freeAddr(t), Handler: New(store.NewMemory())}
+		assert.NoError(t, EnableHTTP2(srv))
+		defer serveTest(t, srv)()
+		url := "http://" + srv.Addr
+		mustCreateEndpoint(t, url, `{"data":{"type":"endpoints","attributes":{
+			"verb":"GET","path":"/h2","match":{"protocol":"HTTP/2.0"},"response":{"code":200}
+		}}}`)
+
+		priorKnowledge := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+		res, err := priorKnowledge.Get(url + "/h2")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "HTTP/2.0", res.Proto)
+
+		res, _ = do(t, http.MethodGet, url+"/h2", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, "HTTP/1.1 requests don't match")
+
+		reqs := getRequests(t, url+"/requests?path=/h2")
+		assert.Equal(t, "HTTP/2.0", reqs.Data[0].Attributes.Protocol)
+		assert.Equal(t, "HTTP/1.1", reqs.Data[1].Attributes.Protocol)
+
+		conn, err := net.Dial("tcp", srv.Addr)
+		assert.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("GET /h2 HTTP/1.1\r\nHost: echo\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAARAAAAAAAIAAAAA\r\n\r\n"))
+		assert.NoError(t, err)
+		upgrade, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, upgrade.StatusCode)
+		assert.Equal(t, "h2c", upgrade.Header.Get("Upgrade"))
+	})
+
+	ca, err := certs.NewCA()
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.PEM())
+	client := &http.Client{Transport: &http.Transport{ForceAttemptHTTP2: true, TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	tests := []struct {
+		name      string
+		configure func(*http.Server)
+		wantProto string
+	}{
+		{
+			name:      "negotiated over TLS",
+			configure: func(srv *http.Server) { assert.NoError(t, EnableHTTP2(srv)) },
+			wantProto: "HTTP/2.0",
+		},
+		{
+			name:      "disabled",
+			configure: DisableHTTP2,
+			wantProto: "HTTP/1.1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := &http.Server{Addr: freeAddr(t), Handler: New(store.NewMemory()), TLSConfig: &tls.Config{GetCertificate: ca.GetCertificate}}
+			test.configure(srv)
+			defer serveTest(t, srv)()
+
+			res, err := client.Get("https://" + srv.Addr + "/requests")
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, test.wantProto, res.Proto)
+		})
+	}
+}
+
+// serveTest serves srv until the returned function is called.
+func serveTest(t *testing.T, srv *http.Server) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- Serve(ctx, time.Second, srv) }()
+	waitListening(t, srv.Addr)
+	return func() {
+		cancel()
+		assert.NoError(t, <-served)
+	}
+}
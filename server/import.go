@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Alvaroalonsobabbel/echo/openapi"
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+type imported struct {
+	Data []*store.Endpoint `json:"data"`
+	Meta struct {
+		Skipped []string `json:"skipped"`
+	} `json:"meta"`
+}
+
+// importEndpoints creates the endpoints described by the document in the
+// request body. Endpoints that are invalid or conflict with existing ones are
+// skipped and reported in the response's meta.
+func (h *handlers) importEndpoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		doc, err := io.ReadAll(r.Body)
+		if err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unable to read request body: %v", err))
+			return
+		}
+		var endpoints []*store.Endpoint
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "openapi":
+			endpoints, err = openapi.Import(doc)
+		case "echo":
+			endpoints, err = ReadBundle(doc)
+		default:
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unsupported import format `%s`", format))
+			return
+		}
+		if err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var allowed []*store.Endpoint
+		res := &imported{}
+		res.Meta.Skipped = []string{}
+		for _, e := range endpoints {
+			if err := h.verify(e); err != nil {
+				res.Meta.Skipped = append(res.Meta.Skipped, fmt.Sprintf("%s %s: %v", e.Attributes.Verb, e.Attributes.Path, err))
+				continue
+			}
+			e.Attributes.Namespace = namespace(r)
+			e.Attributes.Source = ""
+			allowed = append(allowed, e)
+		}
+		created, skipped, err := store.CreateEndpoints(h, allowed)
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to import endpoints: %v", err))
+			return
+		}
+		res.Data = created
+		for _, err := range skipped {
+			res.Meta.Skipped = append(res.Meta.Skipped, err.Error())
+		}
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error encoding endpoints: %v", err))
+			return
+		}
+	}
+}
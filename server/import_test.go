@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+const openAPIDoc = `
+openapi: 3.0.0
+paths:
+  /greeting:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              example: {"message": "Hello, world"}
+  /revert_entropy:
+    get:
+      responses:
+        "200":
+          description: Already seeded.
+`
+
+func TestImportEndpoints(t *testing.T) {
+	s := store.NewMemory()
+	assert.NoError(t, s.Seed())
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+
+	t.Run("POST /endpoints/import creates the endpoints of an OpenAPI document", func(t *testing.T) {
+		res, body := do(t, http.MethodPost, server.URL+"/endpoints/import", openAPIDoc)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		got := &imported{}
+		assert.NoError(t, json.Unmarshal([]byte(body), got))
+		assert.Len(t, got.Data, 1)
+		assert.Equal(t, "/greeting", got.Data[0].Attributes.Path)
+		assert.Equal(t, []string{"GET /revert_entropy: conflicts with endpoint 1"}, got.Meta.Skipped)
+
+		res, body = do(t, http.MethodGet, server.URL+"/greeting", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.JSONEq(t, `{"message":"Hello, world"}`, body)
+	})
+
+	t.Run("endpoints that can't be served are skipped", func(t *testing.T) {
+		res, body := do(t, http.MethodPost, server.URL+"/endpoints/import?format=echo", `{"meta":{"format":"echo","version":2},"data":[
+			{"type":"endpoints","attributes":{"verb":"GET","path":"/template","response":{"code":200,"body":"{{.Path","template":true}}},
+			{"type":"endpoints","attributes":{"verb":"GET","path":"/fixture","response":{"code":200,"file":"missing.png"}}},
+			{"type":"endpoints","attributes":{"verb":"GET","path":"/valid","response":{"code":200}}}
+		]}`)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		got := &imported{}
+		assert.NoError(t, json.Unmarshal([]byte(body), got))
+		assert.Len(t, got.Data, 1)
+		assert.Equal(t, "/valid", got.Data[0].Attributes.Path)
+		assert.Len(t, got.Meta.Skipped, 2)
+		assert.Contains(t, got.Meta.Skipped[0], "GET /template: ")
+		assert.Equal(t, "GET /fixture: unable to serve files: there's no fixtures directory", got.Meta.Skipped[1])
+	})
+
+	t.Run("invalid documents return 400", func(t *testing.T) {
+		res, _ := do(t, http.MethodPost, server.URL+"/endpoints/import", "swagger: '2.0'")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		res, _ = do(t, http.MethodPost, server.URL+"/endpoints/import?format=raml", openAPIDoc)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
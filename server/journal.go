@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/journal"
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// request is the JSON:API representation of a journal entry.
+type request struct {
+	Type       string         `json:"type"`
+	ID         int            `json:"id"`
+	Attributes *journal.Entry `json:"attributes"`
+}
+
+type requests struct {
+	Data []*request `json:"data"`
+}
+
+type count struct {
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+func (h *handlers) record(r *http.Request, body []byte, m *store.Match) {
+	e := &journal.Entry{
+		Namespace:  namespace(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Headers:    r.Header.Clone(),
+		Body:       string(body),
+		Protocol:   r.Proto,
+		ClientCert: clientCert(r),
+		Timestamp:  time.Now().UTC(),
+	}
+	if m != nil {
+		e.EndpointID = m.Endpoint.ID
+	}
+	slog.Debug("request", "namespace", e.Namespace, "method", e.Method, "path", e.Path, "proto", e.Protocol, "endpoint", e.EndpointID)
+	h.journal.Record(e)
+}
+
+func (h *handlers) fetchRequests() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := filterFromQuery(r)
+		if err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.Struct(f); err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		res := &requests{Data: []*request{}}
+		for _, e := range h.journal.Find(f) {
+			res.Data = append(res.Data, &request{Type: "requests", ID: e.ID, Attributes: e})
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error serializing requests: %v", err))
+			return
+		}
+	}
+}
+
+func (h *handlers) countRequests() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := &journal.Filter{Namespace: namespace(r)}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(f); err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unable to decode request body: %v", err))
+			return
+		}
+		if err := h.Struct(f); err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		c := &count{}
+		c.Meta.Count = h.journal.Count(f)
+		if err := json.NewEncoder(w).Encode(c); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error serializing count: %v", err))
+			return
+		}
+	}
+}
+
+func (h *handlers) deleteRequests() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.journal.Delete(&journal.Filter{Namespace: namespace(r)})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// filterFromQuery reads a journal filter from the `method`, `path`,
+// `endpointId` and `matched` query params.
+func filterFromQuery(r *http.Request) (*journal.Filter, error) {
+	q := r.URL.Query()
+	f := &journal.Filter{Namespace: namespace(r), Method: q.Get("method"), Path: q.Get("path")}
+	if id := q.Get("endpointId"); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpointId `%s`", id)
+		}
+		f.EndpointID = n
+	}
+	if matched := q.Get("matched"); matched != "" {
+		b, err := strconv.ParseBool(matched)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matched `%s`", matched)
+		}
+		f.Matched = &b
+	}
+	return f, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"POST","path":"/payments","response":{"code":201}}}}`)
+	for _, amount := range []string{"10", "10", "20"} {
+		res, err := http.Post(server.URL+"/payments?source=test", "application/json", strings.NewReader(`{"amount":`+amount+`}`))
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+	res, err := http.Get(server.URL + "/missing")
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	t.Run("GET /requests returns every request to mocks", func(t *testing.T) {
+		got := getRequests(t, server.URL+"/requests")
+		assert.Len(t, got.Data, 4)
+		assert.Equal(t, "requests", got.Data[0].Type)
+		assert.Equal(t, 1, got.Data[0].ID)
+		assert.Equal(t, http.MethodPost, got.Data[0].Attributes.Method)
+		assert.Equal(t, "/payments", got.Data[0].Attributes.Path)
+		assert.Equal(t, "test", got.Data[0].Attributes.Query.Get("source"))
+		assert.Equal(t, "application/json", got.Data[0].Attributes.Headers.Get("Content-Type"))
+		assert.Equal(t, `{"amount":10}`, got.Data[0].Attributes.Body)
+		assert.Equal(t, 1, got.Data[0].Attributes.EndpointID)
+		assert.False(t, got.Data[0].Attributes.Timestamp.IsZero())
+	})
+
+	t.Run("GET /requests filters requests", func(t *testing.T) {
+		got := getRequests(t, server.URL+"/requests?matched=false")
+		assert.Len(t, got.Data, 1)
+		assert.Equal(t, "/missing", got.Data[0].Attributes.Path)
+		assert.Zero(t, got.Data[0].Attributes.EndpointID)
+
+		got = getRequests(t, server.URL+"/requests?method=POST&path=/payments&endpointId=1")
+		assert.Len(t, got.Data, 3)
+	})
+
+	t.Run("GET /requests with an invalid filter returns 400", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/requests?matched=maybe")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("POST /requests/count counts the matching requests", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/requests/count", "application/json", strings.NewReader(
+			`{"method":"POST","path":"/payments","match":{"body":{"json":{"amount":10}}}}`,
+		))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.JSONEq(t, `{"meta":{"count":2}}`, string(body))
+	})
+
+	t.Run("DELETE /requests empties the journal", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, server.URL+"/requests", nil)
+		assert.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Empty(t, getRequests(t, server.URL+"/requests").Data)
+	})
+}
+
+func getRequests(t testing.TB, url string) *requests {
+	t.Helper()
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	got := &requests{}
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(got))
+	return got
+}
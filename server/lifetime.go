@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// WithReaper deletes the expired endpoints from the storage every interval,
+// until ctx is done or the server is closed. Expired endpoints never match
+// requests, so reaping only keeps the storage from growing.
+func WithReaper(ctx context.Context, interval time.Duration) Option {
+	return func(h *handlers) {
+		h.reaper = func() { h.reap(ctx, interval) }
+	}
+}
+
+func (h *handlers) reap(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.stop.Done():
+			return
+		case now := <-t.C:
+			ids, err := h.DeleteExpired(now)
+			if err != nil {
+				slog.Error("unable to delete expired endpoints", "err", err)
+				continue
+			}
+			for _, id := range ids {
+				h.forget(strconv.Itoa(id))
+			}
+			if len(ids) > 0 {
+				slog.Info("deleted expired endpoints", "count", len(ids))
+			}
+		}
+	}
+}
+
+// forget drops the runtime state of an endpoint that was updated or deleted.
+func (h *handlers) forget(id string) {
+	h.scenarios.forget(id)
+	h.hits.forget(id)
+}
+
+// hits counts the requests served by endpoints with a maximum number of hits.
+// The counts are kept in memory, like the positions of sequences.
+type hits struct {
+	mu     sync.Mutex
+	counts map[int]int
+}
+
+func newHits() *hits {
+	return &hits{counts: map[int]int{}}
+}
+
+// take counts a request served by the endpoint and calls last, with the lock
+// held, when it's the last one the endpoint serves. It returns false when the
+// endpoint has no hits left because other requests took them, calling last
+// again in case it failed then, so that an exhausted endpoint isn't found
+// over and over.
+func (c *hits) take(e *store.Endpoint, last func() error) (bool, error) {
+	if e.Attributes.MaxHits == 0 {
+		return true, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[e.ID] >= e.Attributes.MaxHits {
+		return false, last()
+	}
+	c.counts[e.ID]++
+	if c.counts[e.ID] == e.Attributes.MaxHits {
+		return true, last()
+	}
+	return true, nil
+}
+
+// left returns the number of requests the endpoint can still serve.
+func (c *hits) left(e *store.Endpoint) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return max(e.Attributes.MaxHits-c.counts[e.ID], 0)
+}
+
+func (c *hits) forget(id string) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.counts, n)
+}
+
+// listedEndpoint is an endpoint as listed by GET /endpoints, with its
+// remaining lifetime.
+type listedEndpoint struct {
+	*store.Endpoint
+	Meta *lifetime `json:"meta,omitempty"`
+}
+
+type listedEndpoints struct {
+	Data []*listedEndpoint `json:"data"`
+}
+
+// lifetime is how long an endpoint has left, in seconds, and how many
+// requests it can still serve.
+type lifetime struct {
+	ExpiresIn *int `json:"expiresIn,omitempty"`
+	HitsLeft  *int `json:"hitsLeft,omitempty"`
+}
+
+func (h *handlers) lifetime(e *store.Endpoint, now time.Time) *lifetime {
+	a := e.Attributes
+	if a.ExpiresAt == nil && a.MaxHits == 0 {
+		return nil
+	}
+	l := &lifetime{}
+	if a.ExpiresAt != nil {
+		in := max(int(math.Ceil(a.ExpiresAt.Sub(now).Seconds())), 0)
+		l.ExpiresIn = &in
+	}
+	if a.MaxHits > 0 {
+		left := h.hits.left(e)
+		l.HitsLeft = &left
+	}
+	return l
+}
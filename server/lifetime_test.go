@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxHits(t *testing.T) {
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/once","maxHits":2,"response":{"code":200,"body":"hi"}}}}`)
+
+	_, body := do(t, http.MethodGet, server.URL+"/endpoints", "")
+	assert.Contains(t, body, `"meta":{"hitsLeft":2}`)
+
+	res, _ := do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	_, body = do(t, http.MethodGet, server.URL+"/endpoints", "")
+	assert.Contains(t, body, `"meta":{"hitsLeft":1}`)
+
+	res, _ = do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res, _ = do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	e, err := s.FetchEndpoints()
+	assert.NoError(t, err)
+	assert.Empty(t, e.Data)
+
+	t.Run("concurrent requests don't exceed the hits", func(t *testing.T) {
+		mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/race","maxHits":5,"response":{"code":200}}}}`)
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			served int
+		)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, _ := do(t, http.MethodGet, server.URL+"/race", "")
+				if res.StatusCode == http.StatusOK {
+					mu.Lock()
+					served++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 5, served)
+	})
+}
+
+// failingDelete is a store.Storage whose deletes fail while fail is set.
+type failingDelete struct {
+	store.Storage
+	fail bool
+}
+
+func (s *failingDelete) DeleteEndpoint(id string) (bool, error) {
+	if s.fail {
+		return false, errors.New("storage is down")
+	}
+	return s.Storage.DeleteEndpoint(id)
+}
+
+func TestMaxHitsDeleteErrors(t *testing.T) {
+	s := &failingDelete{Storage: store.NewMemory(), fail: true}
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/once","maxHits":1,"response":{"code":200}}}}`)
+
+	res, _ := do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	res, _ = do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode, "exhausted endpoints that can't be deleted fail instead of looping")
+
+	s.fail = false
+	res, _ = do(t, http.MethodGet, server.URL+"/once", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	e, err := s.FetchEndpoints()
+	assert.NoError(t, err)
+	assert.Empty(t, e.Data)
+}
+
+func TestExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s, WithReaper(ctx, 10*time.Millisecond)))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/ttl","ttl":60,"response":{"code":200}}}}`)
+	expired := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/expired","expiresAt":"`+expired+`","response":{"code":200}}}}`)
+
+	res, _ := do(t, http.MethodGet, server.URL+"/expired", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, _ = do(t, http.MethodGet, server.URL+"/ttl", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Eventually(t, func() bool {
+		e, err := s.FetchEndpoints()
+		return err == nil && len(e.Data) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, body := do(t, http.MethodGet, server.URL+"/endpoints", "")
+	got := struct {
+		Data []struct {
+			Attributes struct {
+				TTL       int        `json:"ttl"`
+				ExpiresAt *time.Time `json:"expiresAt"`
+			} `json:"attributes"`
+			Meta struct {
+				ExpiresIn int `json:"expiresIn"`
+			} `json:"meta"`
+		} `json:"data"`
+	}{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &got))
+	assert.Len(t, got.Data, 1)
+	assert.Zero(t, got.Data[0].Attributes.TTL)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *got.Data[0].Attributes.ExpiresAt, 5*time.Second)
+	assert.Equal(t, 60, got.Data[0].Meta.ExpiresIn)
+
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/mockfile"
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// WithMocksDir loads the endpoints defined in the YAML and JSON files of dir
+// when the server starts, and checks the files for changes every interval
+// until the server is closed. The endpoints of changed files are updated, and
+// those of deleted files are deleted. Files with invalid endpoints are
+// rejected as a whole and keep the endpoints of their last valid version.
+func WithMocksDir(dir string, interval time.Duration) Option {
+	return func(h *handlers) {
+		h.mocks = &mockDir{dir: dir, interval: interval, files: map[string]*mockStatus{}}
+	}
+}
+
+// mockDir is the directory of definition files and the status of each file
+// as of the last check.
+type mockDir struct {
+	dir      string
+	interval time.Duration
+	mu       sync.Mutex
+	files    map[string]*mockStatus
+}
+
+// mockStatus is the outcome of loading a definition file.
+type mockStatus struct {
+	sum       [32]byte
+	endpoints int
+	errors    []string
+}
+
+func (d *mockDir) snapshot() map[string]*mockStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.files
+}
+
+func (d *mockDir) set(files map[string]*mockStatus) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files = files
+}
+
+func (h *handlers) watchMocks() {
+	t := time.NewTicker(h.mocks.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-h.stop.Done():
+			return
+		case <-t.C:
+			h.reloadMocks()
+		}
+	}
+}
+
+// reloadMocks reconciles the storage with the definition files that changed
+// since the last check, and deletes the endpoints of the files that are gone.
+func (h *handlers) reloadMocks() {
+	files, err := mockfile.Read(h.mocks.dir)
+	if err != nil {
+		slog.Error("unable to read the mocks directory", "dir", h.mocks.dir, "err", err)
+		return
+	}
+	all, err := h.FetchEndpoints()
+	if err != nil {
+		slog.Error("unable to fetch endpoints", "err", err)
+		return
+	}
+	loaded := map[string][]*store.Endpoint{}
+	for _, e := range all.Data {
+		if src := e.Attributes.Source; src != "" {
+			loaded[src] = append(loaded[src], e)
+		}
+	}
+
+	previous := h.mocks.snapshot()
+	current := make(map[string]*mockStatus, len(files))
+	for _, f := range files {
+		if p, ok := previous[f.Path]; ok && p.sum == f.Sum {
+			current[f.Path] = p
+			continue
+		}
+		current[f.Path] = h.loadMockFile(f, loaded[f.Path])
+	}
+	for src, endpoints := range loaded {
+		if _, ok := current[src]; ok {
+			continue
+		}
+		for _, e := range endpoints {
+			h.deleteMock(e)
+		}
+		slog.Info("unloaded mock file", "file", src, "endpoints", len(endpoints))
+	}
+	h.mocks.set(current)
+}
+
+// loadMockFile replaces the endpoints loaded from the file, old, with those
+// it defines now. Endpoints are matched by route, so that unchanged routes
+// keep their id.
+func (h *handlers) loadMockFile(f *mockfile.File, old []*store.Endpoint) *mockStatus {
+	status := &mockStatus{sum: f.Sum, endpoints: len(old)}
+	if f.Err != nil {
+		status.errors = []string{f.Err.Error()}
+		slog.Error("invalid mock file", "file", f.Path, "err", f.Err)
+		return status
+	}
+	routes := map[string]bool{}
+	for _, e := range f.Endpoints {
+		err := h.verify(e)
+		if err == nil && routes[routeKey(e)] {
+			err = fmt.Errorf("route `%s` is defined more than once", e.Attributes.Route())
+		}
+		if err != nil {
+			status.errors = append(status.errors, fmt.Sprintf("%s %s: %v", e.Attributes.Verb, e.Attributes.Path, err))
+		}
+		routes[routeKey(e)] = true
+	}
+	if len(status.errors) > 0 {
+		slog.Error("invalid mock file", "file", f.Path, "err", strings.Join(status.errors, "; "))
+		return status
+	}
+
+	byRoute := make(map[string]*store.Endpoint, len(old))
+	for _, e := range old {
+		byRoute[routeKey(e)] = e
+	}
+	status.endpoints = 0
+	now := time.Now()
+	for _, e := range f.Endpoints {
+		e.Attributes.Source = f.Path
+		e.Attributes.SetExpiry(now)
+		if err := h.saveMock(e, byRoute); err != nil {
+			status.errors = append(status.errors, fmt.Sprintf("%s %s: %v", e.Attributes.Verb, e.Attributes.Path, err))
+			continue
+		}
+		status.endpoints++
+	}
+	for _, e := range byRoute {
+		h.deleteMock(e)
+	}
+	if len(status.errors) > 0 {
+		slog.Error("mock file partially loaded", "file", f.Path, "err", strings.Join(status.errors, "; "))
+	}
+	slog.Info("loaded mock file", "file", f.Path, "endpoints", status.endpoints)
+	return status
+}
+
+// saveMock updates the endpoint with the same route in old, and takes it out
+// of old, or creates the endpoint when there's none.
+func (h *handlers) saveMock(e *store.Endpoint, old map[string]*store.Endpoint) error {
+	if o, ok := old[routeKey(e)]; ok {
+		delete(old, routeKey(e))
+		id := strconv.Itoa(o.ID)
+		if _, err := h.UpdateEndpoint(id, e); err != nil {
+			return fmt.Errorf("unable to update endpoint: %v", err)
+		}
+		h.forget(id)
+		return nil
+	}
+	conflict, err := store.FindConflict(h, e)
+	if err != nil {
+		return fmt.Errorf("error finding endpoint: %v", err)
+	}
+	if conflict != nil {
+		if src := conflict.Attributes.Source; src != "" {
+			return fmt.Errorf("conflicts with endpoint %d of `%s`", conflict.ID, src)
+		}
+		return fmt.Errorf("conflicts with endpoint %d", conflict.ID)
+	}
+	if _, err := h.CreateEndpoint(e); err != nil {
+		return fmt.Errorf("unable to create endpoint: %v", err)
+	}
+	return nil
+}
+
+func (h *handlers) deleteMock(e *store.Endpoint) {
+	id := strconv.Itoa(e.ID)
+	if _, err := h.DeleteEndpoint(id); err != nil {
+		slog.Error("unable to delete endpoint", "id", id, "err", err)
+		return
+	}
+	h.forget(id)
+}
+
+// routeKey identifies the route of the endpoint across namespaces.
+func routeKey(e *store.Endpoint) string {
+	return e.Attributes.Namespace + " " + e.Attributes.Route()
+}
+
+// mockFileObject is the JSON:API representation of a definition file.
+type mockFileObject struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Attributes struct {
+		Endpoints int      `json:"endpoints"`
+		Errors    []string `json:"errors,omitempty"`
+	} `json:"attributes"`
+}
+
+type mockFileList struct {
+	Data []*mockFileObject `json:"data"`
+}
+
+// fetchMockFiles lists the definition files of the mocks directory with the
+// number of endpoints loaded from each one and the errors found in it.
+func (h *handlers) fetchMockFiles() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		list := &mockFileList{Data: []*mockFileObject{}}
+		if h.mocks != nil {
+			files := h.mocks.snapshot()
+			for path, status := range files {
+				o := &mockFileObject{Type: "mockfiles", ID: path}
+				o.Attributes.Endpoints = status.endpoints
+				o.Attributes.Errors = status.errors
+				list.Data = append(list.Data, o)
+			}
+			sort.Slice(list.Data, func(i, j int) bool { return list.Data[i].ID < list.Data[j].ID })
+		}
+		if err := json.NewEncoder(w).Encode(list); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error encoding mock files: %v", err))
+			return
+		}
+	}
+}
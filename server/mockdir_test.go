@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMock(t *testing.T, dir, name, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestMocksDir(t *testing.T) {
+	dir := t.TempDir()
+	writeMock(t, dir, "users.yaml", `
+- verb: GET
+  path: /users
+  response: {code: 200, body: v1}
+- verb: DELETE
+  path: /users/{id}
+  response: {code: 204}
+`)
+	writeMock(t, dir, "ns.json", `{"namespace": "ci", "verb": "GET", "path": "/ping", "response": {"code": 200}}`)
+	s := store.NewMemory()
+	srv := New(s, WithMocksDir(dir, time.Hour))
+	defer srv.Close()
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	_, body := do(t, http.MethodGet, server.URL+"/users", "")
+	assert.Equal(t, "v1", body)
+	res, _ := do(t, http.MethodGet, server.URL+"/ns/ci/ping", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	_, body = do(t, http.MethodGet, server.URL+"/mockfiles", "")
+	assert.JSONEq(t, `{"data":[
+		{"type":"mockfiles","id":"ns.json","attributes":{"endpoints":1}},
+		{"type":"mockfiles","id":"users.yaml","attributes":{"endpoints":2}}
+	]}`, body)
+
+	t.Run("file endpoints can't be changed through the API", func(t *testing.T) {
+		res, body := do(t, http.MethodDelete, server.URL+"/endpoints/2", "")
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Contains(t, body, "is defined in `users.yaml`")
+		res, _ = do(t, http.MethodPatch, server.URL+"/endpoints/2", endpointJSON("GET", "/users"))
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+
+	t.Run("changed files update their endpoints", func(t *testing.T) {
+		writeMock(t, dir, "users.yaml", `
+- verb: GET
+  path: /users
+  response: {code: 200, body: v2}
+- verb: POST
+  path: /users
+  response: {code: 201}
+`)
+		srv.handlers.reloadMocks()
+		_, body := do(t, http.MethodGet, server.URL+"/users", "")
+		assert.Equal(t, "v2", body)
+		res, _ := do(t, http.MethodPost, server.URL+"/users", "")
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		res, _ = do(t, http.MethodDelete, server.URL+"/users/1", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		all, err := s.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, all.Data[1].ID, "unchanged routes keep their id")
+		assert.Equal(t, "/users", all.Data[1].Attributes.Path)
+	})
+
+	t.Run("invalid files keep their last valid endpoints", func(t *testing.T) {
+		writeMock(t, dir, "users.yaml", `
+- verb: GET
+  path: /users
+  response: {code: 200, body: v3}
+- verb: GET
+  path: /endpoints
+  response: {code: 999}
+`)
+		srv.handlers.reloadMocks()
+		_, body := do(t, http.MethodGet, server.URL+"/users", "")
+		assert.Equal(t, "v2", body)
+		_, body = do(t, http.MethodGet, server.URL+"/mockfiles", "")
+		assert.Contains(t, body, `{"type":"mockfiles","id":"users.yaml","attributes":{"endpoints":2,"errors":["GET /endpoints: `)
+		assert.Contains(t, body, "'Code' failed on the 'lte' tag")
+	})
+
+	t.Run("conflicting endpoints are skipped", func(t *testing.T) {
+		mustCreateEndpoint(t, server.URL, endpointJSON("GET", "/taken"))
+		writeMock(t, dir, "more.yml", `
+- verb: GET
+  path: /taken
+  response: {code: 200}
+- verb: GET
+  path: /free
+  response: {code: 200}
+`)
+		srv.handlers.reloadMocks()
+		res, _ := do(t, http.MethodGet, server.URL+"/free", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		_, body := do(t, http.MethodGet, server.URL+"/mockfiles", "")
+		assert.Contains(t, body, `{"type":"mockfiles","id":"more.yml","attributes":{"endpoints":1,"errors":["GET /taken: conflicts with endpoint`)
+	})
+
+	t.Run("deleted files delete their endpoints", func(t *testing.T) {
+		assert.NoError(t, os.Remove(filepath.Join(dir, "ns.json")))
+		srv.handlers.reloadMocks()
+		res, _ := do(t, http.MethodGet, server.URL+"/ns/ci/ping", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		_, body := do(t, http.MethodGet, server.URL+"/mockfiles", "")
+		assert.NotContains(t, body, "ns.json")
+	})
+}
+
+func TestMocksDirWatch(t *testing.T) {
+	dir := t.TempDir()
+	srv := New(store.NewMemory(), WithMocksDir(dir, 10*time.Millisecond))
+	defer srv.Close()
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	writeMock(t, dir, "hello.yaml", "verb: GET\npath: /hello\nresponse: {code: 200}")
+	assert.Eventually(t, func() bool {
+		res, _ := do(t, http.MethodGet, server.URL+"/hello", "")
+		return res.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
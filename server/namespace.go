@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/journal"
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+const (
+	// NamespaceHeader sets the namespace of a request, like the /ns/{name}
+	// path prefix does.
+	NamespaceHeader = "X-Echo-Namespace"
+	namespacePrefix = "/ns/"
+)
+
+type namespaceKey struct{}
+
+// withNamespaceMiddleware reads the namespace of the request from the
+// /ns/{name} path prefix, which is removed from the path, or from the
+// X-Echo-Namespace header.
+func withNamespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := r.Header.Get(NamespaceHeader)
+		if rest, ok := strings.CutPrefix(r.URL.Path, namespacePrefix); ok {
+			name, path, _ := strings.Cut(rest, "/")
+			u := *r.URL
+			u.Path, u.RawPath = "/"+path, ""
+			r = r.Clone(r.Context())
+			r.URL = &u
+			ns = name
+		}
+		if ns != "" && !store.ValidNamespace(ns) {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("invalid namespace `%s`", ns))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), namespaceKey{}, ns)))
+	})
+}
+
+// namespace returns the namespace of the request, empty for the default one.
+func namespace(r *http.Request) string {
+	ns, _ := r.Context().Value(namespaceKey{}).(string)
+	return ns
+}
+
+// endpoints returns the endpoints in the namespace.
+func (h *handlers) endpoints(ns string) ([]*store.Endpoint, error) {
+	all, err := h.FetchEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []*store.Endpoint{}
+	for _, e := range all.Data {
+		if e.Attributes.Namespace == ns {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+// owned returns the endpoint with the given id when it's in the namespace of
+// the request, so clients can't change the endpoints of others.
+func (h *handlers) owned(r *http.Request, id string) (*store.Endpoint, error) {
+	endpoints, err := h.endpoints(namespace(r))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range endpoints {
+		if strconv.Itoa(e.ID) == id {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// namespaceObject is the JSON:API representation of a namespace.
+type namespaceObject struct {
+	Type       string `json:"type" validate:"required,oneof=namespaces"`
+	ID         string `json:"id" validate:"required,namespace"`
+	Attributes struct {
+		Endpoints int `json:"endpoints"`
+	} `json:"attributes"`
+}
+
+type namespaceList struct {
+	Data []*namespaceObject `json:"data"`
+}
+
+type namespaceResource struct {
+	Data *namespaceObject `json:"data" validate:"required"`
+}
+
+func newNamespaceObject(ns *store.Namespace) *namespaceObject {
+	o := &namespaceObject{Type: "namespaces", ID: ns.Name}
+	o.Attributes.Endpoints = ns.Endpoints
+	return o
+}
+
+func (h *handlers) fetchNamespaces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespaces, err := h.FetchNamespaces()
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to fetch namespaces: %v", err))
+			return
+		}
+		res := &namespaceList{Data: []*namespaceObject{}}
+		for _, ns := range namespaces {
+			if visible(r, ns.Name) {
+				res.Data = append(res.Data, newNamespaceObject(ns))
+			}
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error serializing namespaces: %v", err))
+			return
+		}
+	}
+}
+
+func (h *handlers) createNamespace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ns := &namespaceResource{}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(ns); err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unable to decode request body: %v", err))
+			return
+		}
+		if err := h.Struct(ns); err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		created, err := h.CreateNamespace(ns.Data.ID)
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to create namespace: %v", err))
+			return
+		}
+		if !created {
+			replyWithErr(w, http.StatusConflict, fmt.Sprintf("the requested namespace `%s` already exists", ns.Data.ID))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(&namespaceResource{Data: newNamespaceObject(&store.Namespace{Name: ns.Data.ID})}); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error encoding namespace: %v", err))
+			return
+		}
+	}
+}
+
+// deleteNamespace tears down a namespace: its endpoints, the requests they
+// received and the state of its scenarios and sequences.
+func (h *handlers) deleteNamespace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		ids, ok, err := h.DeleteNamespace(name)
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to delete namespace: %v", err))
+			return
+		}
+		if !ok {
+			replyWithErr(w, http.StatusNotFound, fmt.Sprintf("Requested Namespace `%s` does not exist", name))
+			return
+		}
+		for _, id := range ids {
+			h.forget(strconv.Itoa(id))
+		}
+		h.scenarios.reset(name, nil)
+		h.journal.Delete(&journal.Filter{Namespace: name})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
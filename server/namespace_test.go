@@ -0,0 +1,93 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	hello := `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/hello","response":{"code":200,"body":"%s"}}}}`
+	mustCreateEndpoint(t, server.URL, strings.Replace(hello, "%s", "default", 1))
+	mustCreateEndpoint(t, server.URL+"/ns/ci-1", strings.Replace(hello, "%s", "ci-1", 1))
+	res, body := doNamespaced(t, "ci-2", http.MethodPost, server.URL+"/endpoints", strings.Replace(hello, "%s", "ci-2", 1))
+	assert.Equal(t, http.StatusCreated, res.StatusCode, body)
+	assert.Contains(t, body, `"namespace":"ci-2"`)
+
+	t.Run("requests are served by the endpoints of their namespace", func(t *testing.T) {
+		_, body := do(t, http.MethodGet, server.URL+"/hello", "")
+		assert.Equal(t, "default", body)
+		_, body = do(t, http.MethodGet, server.URL+"/ns/ci-1/hello", "")
+		assert.Equal(t, "ci-1", body)
+		_, body = doNamespaced(t, "ci-2", http.MethodGet, server.URL+"/hello", "")
+		assert.Equal(t, "ci-2", body)
+		res, _ := do(t, http.MethodGet, server.URL+"/ns/ci-3/hello", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("endpoints and requests are listed by namespace", func(t *testing.T) {
+		_, body := do(t, http.MethodGet, server.URL+"/ns/ci-1/endpoints", "")
+		assert.Contains(t, body, `"body":"ci-1"`)
+		assert.NotContains(t, body, `"body":"default"`)
+		_, body = do(t, http.MethodGet, server.URL+"/ns/ci-1/requests", "")
+		assert.Equal(t, 1, strings.Count(body, `"type":"requests"`))
+		assert.Contains(t, body, `"namespace":"ci-1"`)
+	})
+
+	t.Run("endpoints of other namespaces can't be changed", func(t *testing.T) {
+		res, _ := do(t, http.MethodDelete, server.URL+"/ns/ci-1/endpoints/1", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		res, _ = do(t, http.MethodPatch, server.URL+"/endpoints/2", strings.Replace(hello, "%s", "hijacked", 1))
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("namespaces are created, listed and torn down", func(t *testing.T) {
+		res, _ := do(t, http.MethodPost, server.URL+"/namespaces", `{"data":{"type":"namespaces","id":"ci-3"}}`)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		res, _ = do(t, http.MethodPost, server.URL+"/namespaces", `{"data":{"type":"namespaces","id":"ci-1"}}`)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		res, _ = do(t, http.MethodPost, server.URL+"/namespaces", `{"data":{"type":"namespaces","id":"../etc"}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		_, body := do(t, http.MethodGet, server.URL+"/namespaces", "")
+		assert.Equal(t, `{"data":[`+
+			`{"type":"namespaces","id":"ci-1","attributes":{"endpoints":1}},`+
+			`{"type":"namespaces","id":"ci-2","attributes":{"endpoints":1}},`+
+			`{"type":"namespaces","id":"ci-3","attributes":{"endpoints":0}}]}`, strings.TrimSpace(body))
+
+		res, _ = do(t, http.MethodDelete, server.URL+"/namespaces/ci-1", "")
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		res, _ = do(t, http.MethodGet, server.URL+"/ns/ci-1/hello", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		_, body = do(t, http.MethodGet, server.URL+"/ns/ci-1/requests", "")
+		assert.Equal(t, 1, strings.Count(body, `"type":"requests"`), "only the request after the teardown is left")
+		res, _ = do(t, http.MethodDelete, server.URL+"/namespaces/ci-1", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("invalid namespaces are rejected", func(t *testing.T) {
+		res, _ := doNamespaced(t, "not valid", http.MethodGet, server.URL+"/hello", "")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func doNamespaced(t testing.TB, ns, method, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set(NamespaceHeader, ns)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return res, string(b)
+}
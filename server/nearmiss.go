@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// maxNearMisses is the number of near misses included in a 404 response.
+const maxNearMisses = 3
+
+type nearMissError struct {
+	Errors []nearMissErrorObject `json:"errors"`
+}
+
+type nearMissErrorObject struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+	Meta   struct {
+		NearMisses []*nearMiss `json:"nearMisses"`
+	} `json:"meta"`
+}
+
+type nearMiss struct {
+	ID      int      `json:"id"`
+	Verb    string   `json:"verb"`
+	Path    string   `json:"path"`
+	Reasons []string `json:"reasons"`
+}
+
+// notFound replies to requests that don't match any endpoint. When there are
+// endpoints close to matching the request, they are listed in the error's meta
+// along with what didn't match, to help finding misconfigured mocks.
+func (h *handlers) notFound(w http.ResponseWriter, r *store.Request) {
+	detail := fmt.Sprintf("Requested page `%s` does not exist", r.Path)
+	endpoints, err := h.FetchEndpoints()
+	if err != nil {
+		replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error finding near misses: %v", err))
+		return
+	}
+	misses := store.NearMisses(endpoints.Data, r, maxNearMisses)
+	if len(misses) == 0 {
+		replyWithErr(w, http.StatusNotFound, detail)
+		return
+	}
+
+	res := nearMissErrorObject{Code: http.StatusText(http.StatusNotFound), Detail: detail}
+	explanations := make([]string, 0, len(misses))
+	for _, m := range misses {
+		a := m.Endpoint.Attributes
+		res.Meta.NearMisses = append(res.Meta.NearMisses, &nearMiss{ID: m.Endpoint.ID, Verb: a.Verb, Path: a.Path, Reasons: m.Reasons})
+		explanations = append(explanations, fmt.Sprintf("%s %s (%s)", a.Verb, a.Path, strings.Join(m.Reasons, "; ")))
+	}
+	slog.Info("no endpoint matches", "method", r.Method, "path", r.Path, "closest", strings.Join(explanations, ", "))
+
+	w.WriteHeader(http.StatusNotFound)
+	if err := json.NewEncoder(w).Encode(&nearMissError{Errors: []nearMissErrorObject{res}}); err != nil {
+		slog.Warn("error encoding near misses", "err", err)
+	}
+}
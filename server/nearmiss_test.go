@@ -0,0 +1,35 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearMisses(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/search/books","match":{"query":{"q":"go"}},"response":{"code":200}}}}`)
+
+	res, err := http.Get(server.URL + "/search/books/?q=rust")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "application/vnd.api+json", res.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"errors":[{
+		"code":"Not Found",
+		"detail":"Requested page `+"`/search/books/`"+` does not exist",
+		"meta":{"nearMisses":[{"id":1,"verb":"GET","path":"/search/books","reasons":[
+			"path /search/books/ differs by a trailing slash",
+			"query param `+"`q`"+` is `+"`rust`"+`, expected `+"`go`"+`"
+		]}]}
+	}]}`, string(body))
+}
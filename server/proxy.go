@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// ProxyMode sets what happens to requests that don't match any endpoint.
+type ProxyMode string
+
+const (
+	// Playback replies with 404 to unmatched requests. It's the default mode.
+	Playback ProxyMode = "playback"
+	// Record forwards unmatched requests to the upstream and saves every
+	// exchange as a new endpoint, so it can be replayed offline later on.
+	Record ProxyMode = "record"
+	// Passthrough forwards unmatched requests to the upstream.
+	Passthrough ProxyMode = "passthrough"
+)
+
+// DefaultProxyTimeout is how long the upstream has to reply to forwarded
+// requests by default.
+const DefaultProxyTimeout = 30 * time.Second
+
+// hopHeaders are only meaningful for a single connection, so they are not
+// forwarded nor recorded.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length", "Date",
+}
+
+// ProxyConfig sets how requests that don't match any endpoint are handled.
+type ProxyConfig struct {
+	Mode     ProxyMode `json:"mode" validate:"required,oneof=playback record passthrough"`
+	Upstream string    `json:"upstream" validate:"required_unless=Mode playback,omitempty,http_url"`
+	Key      RecordKey `json:"key"`
+}
+
+// RecordKey lists the parts of a request, besides the verb and the path,
+// that become matchers of the endpoints created in Record mode.
+type RecordKey struct {
+	Query   bool     `json:"query"`
+	Headers []string `json:"headers"`
+	Body    bool     `json:"body"`
+}
+
+type proxyResource struct {
+	Data struct {
+		Type       string       `json:"type" validate:"required,oneof=proxy"`
+		Attributes *ProxyConfig `json:"attributes" validate:"required"`
+	} `json:"data"`
+}
+
+// WithProxy sets the initial proxy configuration, which can be changed later
+// on through the `/proxy` endpoint.
+func WithProxy(c ProxyConfig) Option {
+	return func(h *handlers) {
+		h.proxy.config = c
+	}
+}
+
+// WithProxyTimeout sets how long the upstream has to reply to forwarded
+// requests.
+func WithProxyTimeout(d time.Duration) Option {
+	return func(h *handlers) {
+		h.proxy.client.Timeout = d
+	}
+}
+
+// proxy holds the current proxy configuration.
+type proxy struct {
+	mu     sync.RWMutex
+	config ProxyConfig
+	client *http.Client
+	// recording serializes the recordings, so that concurrent requests to
+	// the same route are only saved once.
+	recording sync.Mutex
+}
+
+func newProxy() *proxy {
+	return &proxy{
+		config: ProxyConfig{Mode: Playback},
+		client: &http.Client{Timeout: DefaultProxyTimeout},
+	}
+}
+
+func (p *proxy) get() ProxyConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+func (p *proxy) set(c ProxyConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = c
+}
+
+func (h *handlers) fetchProxy() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		h.replyWithProxy(w)
+	}
+}
+
+func (h *handlers) updateProxy() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := &proxyResource{}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unable to decode request body: %v", err))
+			return
+		}
+		if err := h.Struct(p); err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.proxy.set(*p.Data.Attributes)
+		h.replyWithProxy(w)
+	}
+}
+
+func (h *handlers) replyWithProxy(w http.ResponseWriter) {
+	p := &proxyResource{}
+	c := h.proxy.get()
+	p.Data.Type = "proxy"
+	p.Data.Attributes = &c
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error encoding proxy: %v", err))
+	}
+}
+
+// forward sends the request to the upstream and copies back its response. In
+// Record mode the exchange is saved as a new endpoint.
+func (h *handlers) forward(w http.ResponseWriter, r *http.Request, body []byte, c ProxyConfig) {
+	upstream, err := url.Parse(c.Upstream)
+	if err != nil {
+		replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("invalid upstream: %v", err))
+		return
+	}
+	target := upstream.JoinPath(r.URL.Path)
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to create upstream request: %v", err))
+		return
+	}
+	req.Header = r.Header.Clone()
+	removeHopHeaders(req.Header)
+	// Let the transport negotiate compression, so recorded bodies are decoded.
+	req.Header.Del("Accept-Encoding")
+
+	res, err := h.proxy.client.Do(req)
+	if err != nil {
+		replyWithErr(w, http.StatusBadGateway, fmt.Sprintf("Upstream `%s` is not available", c.Upstream))
+		return
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		replyWithErr(w, http.StatusBadGateway, fmt.Sprintf("Unable to read the response from upstream `%s`", c.Upstream))
+		return
+	}
+	removeHopHeaders(res.Header)
+
+	w.Header().Del("Content-Type")
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.StatusCode)
+	if _, err := w.Write(resBody); err != nil {
+		slog.Warn("error writing upstream response", "err", err)
+	}
+
+	if c.Mode == Record {
+		if err := h.recordExchange(r, body, res, resBody, c.Key); err != nil {
+			slog.Error("unable to record", "method", r.Method, "path", r.URL.Path, "err", err)
+		}
+	}
+}
+
+func (h *handlers) recordExchange(r *http.Request, body []byte, res *http.Response, resBody []byte, key RecordKey) error {
+	e := &store.Endpoint{
+		Type: "endpoints",
+		Attributes: store.Attributes{
+			Namespace: namespace(r),
+			Verb:      r.Method,
+			Path:      r.URL.Path,
+			Match:     recordMatchers(r, body, key),
+			Response: store.Response{
+				Code:     res.StatusCode,
+				Headers:  store.Headers(res.Header.Clone()),
+				Body:     string(resBody),
+				Encoding: store.TextEncoding,
+			},
+		},
+	}
+	if !utf8.Valid(resBody) {
+		e.Attributes.Response.Body = base64.StdEncoding.EncodeToString(resBody)
+		e.Attributes.Response.Encoding = store.Base64Encoding
+	}
+	if err := h.verify(e); err != nil {
+		return err
+	}
+
+	h.proxy.recording.Lock()
+	defer h.proxy.recording.Unlock()
+	conflict, err := store.FindConflict(h, e)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		slog.Info("already recorded", "method", r.Method, "path", r.URL.Path, "endpoint", conflict.ID)
+		return nil
+	}
+	created, err := h.CreateEndpoint(e)
+	if err != nil {
+		return err
+	}
+	slog.Info("recorded", "method", r.Method, "path", r.URL.Path, "endpoint", created.Data.ID)
+	return nil
+}
+
+// recordMatchers returns the matchers for the parts of the request in the key.
+func recordMatchers(r *http.Request, body []byte, key RecordKey) *store.Matchers {
+	m := &store.Matchers{}
+	if key.Query && len(r.URL.Query()) > 0 {
+		m.Query = map[string]store.ValueMatcher{}
+		for k := range r.URL.Query() {
+			m.Query[k] = store.ValueMatcher{Equals: r.URL.Query().Get(k)}
+		}
+	}
+	for _, k := range key.Headers {
+		if m.Headers == nil {
+			m.Headers = map[string]store.ValueMatcher{}
+		}
+		if v := r.Header.Get(k); v != "" {
+			m.Headers[k] = store.ValueMatcher{Equals: v}
+			continue
+		}
+		absent := false
+		m.Headers[k] = store.ValueMatcher{Present: &absent}
+	}
+	if key.Body && len(body) > 0 {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err == nil && doc != nil {
+			m.Body = &store.BodyMatcher{JSONEquals: doc}
+		} else {
+			m.Body = &store.BodyMatcher{Matches: "^" + regexp.QuoteMeta(string(body)) + "$"}
+		}
+	}
+	if m.Query == nil && m.Headers == nil && m.Body == nil {
+		return nil
+	}
+	return m
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy(t *testing.T) {
+	upstreamCalls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusAccepted)
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, `{"path":"`+r.URL.Path+`","q":"`+r.URL.Query().Get("q")+`","body":"`+string(body)+`"}`) //nolint:errcheck
+	}))
+	defer upstream.Close()
+
+	t.Run("Passthrough forwards unmatched requests without recording them", func(t *testing.T) {
+		s := store.NewMemory()
+		server := httptest.NewServer(New(s, WithProxy(ProxyConfig{Mode: Passthrough, Upstream: upstream.URL})))
+		defer server.Close()
+
+		res, body := do(t, http.MethodGet, server.URL+"/users?q=1", "")
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.Equal(t, "yes", res.Header.Get("X-Upstream"))
+		assert.Equal(t, `{"path":"/users","q":"1","body":""}`, body)
+
+		e, err := s.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Empty(t, e.Data)
+	})
+
+	t.Run("Record saves upstream exchanges that are replayed later on", func(t *testing.T) {
+		s := store.NewMemory()
+		server := httptest.NewServer(New(s, WithProxy(ProxyConfig{
+			Mode:     Record,
+			Upstream: upstream.URL,
+			Key:      RecordKey{Query: true, Body: true},
+		})))
+		defer server.Close()
+
+		_, first := do(t, http.MethodPost, server.URL+"/orders?q=a", "one")
+		_, second := do(t, http.MethodPost, server.URL+"/orders?q=b", "two")
+		calls := upstreamCalls
+
+		e, err := s.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Len(t, e.Data, 2)
+		assert.Equal(t, &store.Matchers{
+			Query: map[string]store.ValueMatcher{"q": {Equals: "a"}},
+			Body:  &store.BodyMatcher{Matches: "^one$"},
+		}, e.Data[0].Attributes.Match)
+
+		put(t, server.URL+"/proxy", `{"data":{"type":"proxy","attributes":{"mode":"playback"}}}`)
+		res, body := do(t, http.MethodPost, server.URL+"/orders?q=b", "two")
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "yes", res.Header.Get("X-Upstream"))
+		assert.Equal(t, second, body)
+		res, body = do(t, http.MethodPost, server.URL+"/orders?q=a", "one")
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, first, body)
+		res, _ = do(t, http.MethodPost, server.URL+"/orders?q=c", "one")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, calls, upstreamCalls)
+	})
+
+	t.Run("Record saves each route once", func(t *testing.T) {
+		s := store.NewMemory()
+		srv := New(s, WithProxy(ProxyConfig{Mode: Record, Upstream: upstream.URL}))
+		defer srv.Close()
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+		for range 2 {
+			assert.NoError(t, srv.handlers.recordExchange(r, nil, res, []byte("users"), RecordKey{}))
+		}
+
+		e, err := s.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Len(t, e.Data, 1)
+
+		res.StatusCode = 999
+		assert.Error(t, srv.handlers.recordExchange(httptest.NewRequest(http.MethodGet, "/other", nil), nil, res, nil, RecordKey{}))
+	})
+
+	t.Run("unavailable upstreams return 502", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		server := httptest.NewServer(New(store.NewMemory(), WithProxy(ProxyConfig{Mode: Passthrough, Upstream: down.URL})))
+		defer server.Close()
+
+		res, _ := do(t, http.MethodGet, server.URL+"/users", "")
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	})
+
+	t.Run("GET and PUT /proxy manage the configuration", func(t *testing.T) {
+		server := httptest.NewServer(New(store.NewMemory()))
+		defer server.Close()
+
+		_, body := do(t, http.MethodGet, server.URL+"/proxy", "")
+		assert.JSONEq(t, `{"data":{"type":"proxy","attributes":{"mode":"playback","upstream":"","key":{"query":false,"headers":null,"body":false}}}}`, body)
+
+		res, _ := do(t, http.MethodPut, server.URL+"/proxy", `{"data":{"type":"proxy","attributes":{"mode":"record"}}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res, body = do(t, http.MethodPut, server.URL+"/proxy", `{"data":{"type":"proxy","attributes":{"mode":"record","upstream":"http://localhost:8080","key":{"headers":["Authorization"]}}}}`)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.JSONEq(t, `{"data":{"type":"proxy","attributes":{"mode":"record","upstream":"http://localhost:8080","key":{"query":false,"headers":["Authorization"],"body":false}}}}`, body)
+	})
+}
+
+func TestRecordMatchers(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/?a=1", nil)
+	r.Header.Set("Authorization", "token")
+	absent := false
+
+	assert.Nil(t, recordMatchers(r, []byte(`{"x":1}`), RecordKey{}))
+	assert.Equal(t, &store.Matchers{
+		Query:   map[string]store.ValueMatcher{"a": {Equals: "1"}},
+		Headers: map[string]store.ValueMatcher{"Authorization": {Equals: "token"}, "X-Missing": {Present: &absent}},
+		Body:    &store.BodyMatcher{JSONEquals: map[string]any{"x": 1.0}},
+	}, recordMatchers(r, []byte(`{"x":1}`), RecordKey{Query: true, Headers: []string{"Authorization", "X-Missing"}, Body: true}))
+}
+
+// do sends a request and returns the response along with its body.
+func do(t testing.TB, method, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return res, string(b)
+}
+
+func put(t testing.TB, url, body string) {
+	t.Helper()
+	res, _ := do(t, http.MethodPut, url, body)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
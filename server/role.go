@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// Role is the level of access a credential grants. Each role can do
+// everything the previous one does.
+type Role int
+
+const (
+	// Viewer reads the endpoints, requests, scenarios and configuration.
+	Viewer Role = iota + 1
+	// Editor also creates, changes and deletes endpoints, requests and the
+	// state of scenarios.
+	Editor
+	// Admin also creates and tears down namespaces and changes the proxy.
+	Admin
+)
+
+var roles = map[string]Role{"viewer": Viewer, "editor": Editor, "admin": Admin}
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, error) {
+	r, ok := roles[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown role %q", name)
+	}
+	return r, nil
+}
+
+func (r Role) String() string {
+	for name, role := range roles {
+		if role == r {
+			return name
+		}
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+func (r *Role) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	role, err := ParseRole(name)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// Grant is the role a credential has in some namespaces. Grants of API keys
+// and users without a role are admin grants, while tokens must carry a role.
+// Grants without namespaces cover every namespace and the server-wide
+// settings. Grants limited to some namespaces don't cover the default one.
+type Grant struct {
+	Role       Role     `json:"role"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// ParseGrant reads a grant written as `role[:namespace+namespace...]`.
+func ParseGrant(s string) (Grant, error) {
+	name, namespaces, restricted := strings.Cut(s, ":")
+	role, err := ParseRole(name)
+	if err != nil {
+		return Grant{}, err
+	}
+	g := Grant{Role: role}
+	if restricted {
+		g.Namespaces = strings.Split(namespaces, "+")
+		for _, ns := range g.Namespaces {
+			if !store.ValidNamespace(ns) {
+				return Grant{}, fmt.Errorf("invalid namespace %q", ns)
+			}
+		}
+	}
+	return g, nil
+}
+
+// allows reports whether the grant has the role in the namespace, or in any
+// of its namespaces for anyNamespace.
+func (g Grant) allows(ns string, role Role) bool {
+	if g.Role != 0 && g.Role < role {
+		return false
+	}
+	return len(g.Namespaces) == 0 || ns == anyNamespace || (ns != "" && slices.Contains(g.Namespaces, ns))
+}
+
+type grantKey struct{}
+
+// permitted reports whether the credentials of the request have the role in
+// the namespace, replying with 403 Forbidden when they don't. Server-wide
+// settings are checked against the default namespace. Requests are always
+// permitted when the API is open.
+func permitted(w http.ResponseWriter, r *http.Request, ns string, role Role) bool {
+	g, ok := r.Context().Value(grantKey{}).(Grant)
+	if !ok || g.allows(ns, role) {
+		return true
+	}
+	where := "the default namespace"
+	if ns != "" {
+		where = fmt.Sprintf("the namespace `%s`", ns)
+	}
+	replyWithErr(w, http.StatusForbidden, fmt.Sprintf("the credentials don't grant the %s role in %s", role, where))
+	return false
+}
+
+// visible reports whether the credentials of the request can see the
+// namespace.
+func visible(r *http.Request, ns string) bool {
+	g, ok := r.Context().Value(grantKey{}).(Grant)
+	return !ok || g.allows(ns, Viewer)
+}
+
+// authorize authenticates the requests to next and requires the role in the
+// namespace the request acts on, as returned by in.
+func (h *handlers) authorize(role Role, in func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return h.authenticate(func(w http.ResponseWriter, r *http.Request) {
+		if permitted(w, r, in(r), role) {
+			next(w, r)
+		}
+	})
+}
+
+// anyNamespace stands for any of the namespaces a grant covers. It's not a
+// valid namespace name.
+const anyNamespace = "*"
+
+// anywhere is the namespace of routes that every credential can use in the
+// namespaces it covers, like listing them.
+func anywhere(*http.Request) string {
+	return anyNamespace
+}
+
+// serverWide is the namespace of the settings shared by all namespaces, like
+// the proxy, which are checked against the default one.
+func serverWide(*http.Request) string {
+	return ""
+}
+
+// namedNamespace is the namespace named in the path of the request.
+func namedNamespace(r *http.Request) string {
+	return r.PathValue("name")
+}
+
+// createdNamespace is the namespace a request creates, read from its body,
+// which is left to be read again. Bodies that can't be read are checked
+// against the default namespace and rejected by the handler.
+func createdNamespace(r *http.Request) string {
+	b, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	ns := &namespaceResource{}
+	if err != nil || json.Unmarshal(b, ns) != nil || ns.Data == nil {
+		return ""
+	}
+	return ns.Data.ID
+}
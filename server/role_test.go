@@ -0,0 +1,144 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGrant(t *testing.T) {
+	tests := []struct {
+		grant   string
+		want    Grant
+		wantErr bool
+	}{
+		{grant: "viewer", want: Grant{Role: Viewer}},
+		{grant: "editor:ci-1", want: Grant{Role: Editor, Namespaces: []string{"ci-1"}}},
+		{grant: "admin:ci-1+ci-2", want: Grant{Role: Admin, Namespaces: []string{"ci-1", "ci-2"}}},
+		{grant: "owner", wantErr: true},
+		{grant: "editor:", wantErr: true},
+		{grant: "editor:ci/1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.grant, func(t *testing.T) {
+			g, err := ParseGrant(test.grant)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, g)
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory(), WithAuth(AuthConfig{
+		APIKeys: map[string]Grant{
+			"viewer":    {Role: Viewer},
+			"editor":    {Role: Editor},
+			"admin":     {Role: Admin},
+			"ci-editor": {Role: Editor, Namespaces: []string{"ci-1"}},
+			"ci-admin":  {Role: Admin, Namespaces: []string{"ci-1"}},
+		},
+		JWTSecret: jwtSecret,
+	}), WithCA([]byte("ca"))))
+	defer server.Close()
+
+	endpoint := `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/hello","response":{"code":200}}}}`
+	for _, url := range []string{server.URL, server.URL + "/ns/ci-1", server.URL + "/ns/ci-2"} {
+		res, _ := doWithKey(t, "admin", http.MethodPost, url+"/endpoints", endpoint)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "viewers list endpoints", key: "viewer", method: http.MethodGet, path: "/endpoints", want: http.StatusOK},
+		{name: "viewers list requests", key: "viewer", method: http.MethodGet, path: "/requests", want: http.StatusOK},
+		{name: "viewers read the proxy", key: "viewer", method: http.MethodGet, path: "/proxy", want: http.StatusOK},
+		{name: "viewers can't create endpoints", key: "viewer", method: http.MethodPost, path: "/endpoints", body: endpoint, want: http.StatusForbidden},
+		{name: "viewers can't delete endpoints", key: "viewer", method: http.MethodDelete, path: "/endpoints/1", want: http.StatusForbidden},
+		{name: "viewers can't change scenarios", key: "viewer", method: http.MethodDelete, path: "/scenarios", want: http.StatusForbidden},
+		{name: "editors update endpoints", key: "editor", method: http.MethodPatch, path: "/endpoints/1", body: endpoint, want: http.StatusCreated},
+		{name: "editors clear requests", key: "editor", method: http.MethodDelete, path: "/requests", want: http.StatusNoContent},
+		{name: "editors can't change the proxy", key: "editor", method: http.MethodPut, path: "/proxy", body: `{"data":{"type":"proxy","attributes":{"mode":"playback"}}}`, want: http.StatusForbidden},
+		{name: "editors can't create namespaces", key: "editor", method: http.MethodPost, path: "/namespaces", body: `{"data":{"type":"namespaces","id":"ci-3"}}`, want: http.StatusForbidden},
+		{name: "admins change the proxy", key: "admin", method: http.MethodPut, path: "/proxy", body: `{"data":{"type":"proxy","attributes":{"mode":"playback"}}}`, want: http.StatusOK},
+		{name: "admins create namespaces", key: "admin", method: http.MethodPost, path: "/namespaces", body: `{"data":{"type":"namespaces","id":"ci-3"}}`, want: http.StatusCreated},
+		{name: "namespace grants cover their namespace", key: "ci-editor", method: http.MethodPatch, path: "/ns/ci-1/endpoints/2", body: endpoint, want: http.StatusCreated},
+		{name: "namespace grants don't cover other namespaces", key: "ci-editor", method: http.MethodGet, path: "/ns/ci-2/endpoints", want: http.StatusForbidden},
+		{name: "namespace grants don't cover the default namespace", key: "ci-editor", method: http.MethodGet, path: "/endpoints", want: http.StatusForbidden},
+		{name: "namespace admins can't change the proxy", key: "ci-admin", method: http.MethodPut, path: "/ns/ci-1/proxy", body: `{"data":{"type":"proxy","attributes":{"mode":"playback"}}}`, want: http.StatusForbidden},
+		{name: "namespace admins can't tear down other namespaces", key: "ci-admin", method: http.MethodDelete, path: "/namespaces/ci-2", want: http.StatusForbidden},
+		{name: "namespace grants can't read the mock files", key: "ci-admin", method: http.MethodGet, path: "/mockfiles", want: http.StatusForbidden},
+		{name: "namespace grants fetch the CA", key: "ci-editor", method: http.MethodGet, path: "/tls/ca", want: http.StatusOK},
+		{name: "namespace admins can't create other namespaces", key: "ci-admin", method: http.MethodPost, path: "/namespaces", body: `{"data":{"type":"namespaces","id":"ci-4"}}`, want: http.StatusForbidden},
+		{name: "namespace admins tear down their namespace", key: "ci-admin", method: http.MethodDelete, path: "/namespaces/ci-1", want: http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, body := doWithKey(t, test.key, test.method, server.URL+test.path, test.body)
+			assert.Equal(t, test.want, res.StatusCode, body)
+			if test.want == http.StatusForbidden {
+				assert.Contains(t, body, `"code":"Forbidden"`)
+			}
+		})
+	}
+
+	t.Run("namespaces are listed by grant", func(t *testing.T) {
+		_, body := doWithKey(t, "ci-admin", http.MethodGet, server.URL+"/namespaces", "")
+		assert.NotContains(t, body, `"id":"ci-2"`)
+		_, body = doWithKey(t, "admin", http.MethodGet, server.URL+"/namespaces", "")
+		assert.Contains(t, body, `"id":"ci-2"`)
+	})
+
+	t.Run("tokens carry their grant", func(t *testing.T) {
+		token := signJWT(t, "HS256", jwtSecret, map[string]any{"role": "viewer", "namespaces": []string{"ci-2"}})
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/ns/ci-2/endpoints", nil)
+		assert.NoError(t, err)
+		bearer(token)(req)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		req, err = http.NewRequest(http.MethodPost, server.URL+"/ns/ci-2/endpoints", strings.NewReader(endpoint))
+		assert.NoError(t, err)
+		bearer(token)(req)
+		res, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("mock endpoints are open", func(t *testing.T) {
+		res, _ := do(t, http.MethodGet, server.URL+"/ns/ci-2/hello", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func doWithKey(t testing.TB, key, method, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set(APIKeyHeader, key)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return res, string(b)
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// scenario is the JSON:API representation of a scenario's state.
+type scenario struct {
+	Type       string `json:"type" validate:"required,oneof=scenarios"`
+	ID         string `json:"id"`
+	Attributes struct {
+		State string `json:"state" validate:"required"`
+	} `json:"attributes"`
+}
+
+type scenarioList struct {
+	Data []*scenario `json:"data"`
+}
+
+type scenarioResource struct {
+	Data *scenario `json:"data" validate:"required"`
+}
+
+// scenarios holds the state of the scenarios, by namespace, and the position
+// of every endpoint in its sequence of responses.
+type scenarios struct {
+	mu     sync.Mutex
+	states map[string]map[string]string
+	hits   map[int]int
+	// weights holds the current weights of round-robin sequences.
+	weights map[int][]int
+	intN    func(n int) int
+}
+
+func newScenarios() *scenarios {
+	return &scenarios{
+		states:  map[string]map[string]string{},
+		hits:    map[int]int{},
+		weights: map[int][]int{},
+		intN:    rand.IntN,
+	}
+}
+
+// snapshot returns a copy of the current states in the namespace.
+func (s *scenarios) snapshot(ns string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make(map[string]string, len(s.states[ns]))
+	for k, v := range s.states[ns] {
+		states[k] = v
+	}
+	return states
+}
+
+// next returns the response the endpoint serves to the current request and
+// moves its scenario to the new state, if any.
+func (s *scenarios) next(e *store.Endpoint) *store.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sc := e.Attributes.Scenario; sc != nil && sc.NewState != "" {
+		s.setLocked(e.Attributes.Namespace, sc.Name, sc.NewState)
+	}
+	seq := e.Attributes.Sequence
+	if seq == nil {
+		return &e.Attributes.Response
+	}
+
+	hits := s.hits[e.ID]
+	s.hits[e.ID]++
+	n := len(seq.Responses)
+	switch seq.Mode {
+	case store.Cycle:
+		return &seq.Responses[hits%n]
+	case store.RoundRobin:
+		return &seq.Responses[s.roundRobin(e.ID, seq.Responses)]
+	case store.WeightedRandom:
+		return &seq.Responses[s.weightedRandom(seq.Responses)]
+	default:
+		return &seq.Responses[min(hits, n-1)]
+	}
+}
+
+// roundRobin implements smooth weighted round-robin: every response gains its
+// weight on each request and the one with the highest current weight is
+// served, losing the total weight.
+func (s *scenarios) roundRobin(id int, responses []store.Response) int {
+	current := s.weights[id]
+	if len(current) != len(responses) {
+		current = make([]int, len(responses))
+		s.weights[id] = current
+	}
+	best, total := 0, 0
+	for i, r := range responses {
+		current[i] += weight(r)
+		total += weight(r)
+		if current[i] > current[best] {
+			best = i
+		}
+	}
+	current[best] -= total
+	return best
+}
+
+func (s *scenarios) weightedRandom(responses []store.Response) int {
+	total := 0
+	for _, r := range responses {
+		total += weight(r)
+	}
+	n := s.intN(total)
+	for i, r := range responses {
+		if n -= weight(r); n < 0 {
+			return i
+		}
+	}
+	return len(responses) - 1
+}
+
+func weight(r store.Response) int {
+	return max(r.Weight, 1)
+}
+
+// forget restarts the sequence of an endpoint, which is needed when it's
+// updated or deleted.
+func (s *scenarios) forget(id string) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hits, n)
+	delete(s.weights, n)
+}
+
+func (s *scenarios) set(ns, name, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setLocked(ns, name, state)
+}
+
+func (s *scenarios) setLocked(ns, name, state string) {
+	if s.states[ns] == nil {
+		s.states[ns] = map[string]string{}
+	}
+	s.states[ns][name] = state
+}
+
+// reset moves every scenario of the namespace back to the started state and
+// restarts the sequences of the given endpoints.
+func (s *scenarios) reset(ns string, ids []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, ns)
+	for _, id := range ids {
+		delete(s.hits, id)
+		delete(s.weights, id)
+	}
+}
+
+// fetchScenarios lists the scenarios used by the endpoints of the namespace
+// along with those that were set through the API.
+func (h *handlers) fetchScenarios() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := h.endpoints(namespace(r))
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to fetch endpoints: %v", err))
+			return
+		}
+		states := h.scenarios.snapshot(namespace(r))
+		for _, e := range endpoints {
+			if sc := e.Attributes.Scenario; sc != nil {
+				if _, ok := states[sc.Name]; !ok {
+					states[sc.Name] = store.StartedState
+				}
+			}
+		}
+		names := make([]string, 0, len(states))
+		for name := range states {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		res := &scenarioList{Data: []*scenario{}}
+		for _, name := range names {
+			res.Data = append(res.Data, newScenario(name, states[name]))
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error serializing scenarios: %v", err))
+			return
+		}
+	}
+}
+
+// updateScenario moves a scenario to the given state.
+func (h *handlers) updateScenario() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sc := &scenarioResource{}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(sc); err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("Unable to decode request body: %v", err))
+			return
+		}
+		if err := h.Struct(sc); err != nil {
+			replyWithErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		name := r.PathValue("name")
+		h.scenarios.set(namespace(r), name, sc.Data.Attributes.State)
+		if err := json.NewEncoder(w).Encode(&scenarioResource{Data: newScenario(name, sc.Data.Attributes.State)}); err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error encoding scenario: %v", err))
+			return
+		}
+	}
+}
+
+// resetScenarios moves every scenario of the namespace back to the started
+// state and restarts the sequences of its endpoints.
+func (h *handlers) resetScenarios() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := h.endpoints(namespace(r))
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to fetch endpoints: %v", err))
+			return
+		}
+		ids := make([]int, 0, len(endpoints))
+		for _, e := range endpoints {
+			ids = append(ids, e.ID)
+		}
+		h.scenarios.reset(namespace(r), ids)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newScenario(name, state string) *scenario {
+	sc := &scenario{Type: "scenarios", ID: name}
+	sc.Attributes.State = state
+	return sc
+}
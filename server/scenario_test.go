@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequences(t *testing.T) {
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+
+	sequence := `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/%s","sequence":{"mode":"%s","responses":[{"code":202,"body":"pending"},{"code":500,"weight":2},{"code":200,"body":"done"}]}}}}`
+	for _, mode := range []string{"stick-on-last", "cycle", "round-robin"} {
+		mustCreateEndpoint(t, server.URL, fmt.Sprintf(sequence, mode, mode))
+	}
+
+	tests := []struct {
+		mode string
+		want []int
+	}{
+		{mode: "stick-on-last", want: []int{202, 500, 200, 200, 200}},
+		{mode: "cycle", want: []int{202, 500, 200, 202, 500}},
+		{mode: "round-robin", want: []int{500, 202, 200, 500, 500, 202}},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			got := []int{}
+			for range test.want {
+				res, _ := do(t, http.MethodGet, server.URL+"/"+test.mode, "")
+				got = append(got, res.StatusCode)
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+
+	t.Run("DELETE /scenarios restarts the sequences", func(t *testing.T) {
+		res, _ := do(t, http.MethodDelete, server.URL+"/scenarios", "")
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		res, body := do(t, http.MethodGet, server.URL+"/stick-on-last", "")
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "pending", body)
+	})
+}
+
+func TestWeightedRandom(t *testing.T) {
+	sc := newScenarios()
+	rolls := []int{0, 1, 2, 3}
+	sc.intN = func(n int) int {
+		assert.Equal(t, 4, n)
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return roll
+	}
+	e := &store.Endpoint{ID: 1, Attributes: store.Attributes{Sequence: &store.Sequence{
+		Mode:      store.WeightedRandom,
+		Responses: []store.Response{{Code: 200}, {Code: 500, Weight: 3}},
+	}}}
+	got := []int{}
+	for range 4 {
+		got = append(got, sc.next(e).Code)
+	}
+	assert.Equal(t, []int{200, 500, 500, 500}, got)
+}
+
+func TestScenarios(t *testing.T) {
+	s := store.NewMemory()
+	server := httptest.NewServer(New(s))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/cart","response":{"code":200,"body":"empty"}}}}`)
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"POST","path":"/cart","scenario":{"name":"checkout","newState":"filled"},"response":{"code":201}}}}`)
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/cart","scenario":{"name":"checkout","requiredState":"filled"},"response":{"code":200,"body":"1 item"}}}}`)
+
+	_, body := do(t, http.MethodGet, server.URL+"/cart", "")
+	assert.Equal(t, "empty", body)
+	res, _ := do(t, http.MethodPost, server.URL+"/cart", "")
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	_, body = do(t, http.MethodGet, server.URL+"/cart", "")
+	assert.Equal(t, "1 item", body)
+
+	t.Run("GET /scenarios lists the states", func(t *testing.T) {
+		res, body := do(t, http.MethodGet, server.URL+"/scenarios", "")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.JSONEq(t, `{"data":[{"type":"scenarios","id":"checkout","attributes":{"state":"filled"}}]}`, body)
+	})
+
+	t.Run("PUT /scenarios/{name} sets the state", func(t *testing.T) {
+		put(t, server.URL+"/scenarios/checkout", `{"data":{"type":"scenarios","attributes":{"state":"Started"}}}`)
+		_, body := do(t, http.MethodGet, server.URL+"/cart", "")
+		assert.Equal(t, "empty", body)
+
+		res, _ := do(t, http.MethodPut, server.URL+"/scenarios/checkout", `{"data":{"type":"scenarios","attributes":{}}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("endpoints requiring other states are reported as near misses", func(t *testing.T) {
+		s := store.NewMemory()
+		server := httptest.NewServer(New(s))
+		defer server.Close()
+		mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/cart","scenario":{"name":"checkout","requiredState":"filled"},"response":{"code":200}}}}`)
+
+		res, body := do(t, http.MethodGet, server.URL+"/cart", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		got := struct {
+			Errors []struct {
+				Meta struct {
+					NearMisses []struct {
+						Reasons []string `json:"reasons"`
+					} `json:"nearMisses"`
+				} `json:"meta"`
+			} `json:"errors"`
+		}{}
+		assert.NoError(t, json.Unmarshal([]byte(body), &got))
+		assert.Equal(t, []string{"scenario `checkout` is in state `Started`, expected `filled`"}, got.Errors[0].Meta.NearMisses[0].Reasons)
+	})
+}
@@ -1,12 +1,17 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Alvaroalonsobabbel/echo/journal"
 	"github.com/Alvaroalonsobabbel/echo/store"
 	"github.com/go-playground/validator/v10"
 )
@@ -16,21 +21,122 @@ const (
 	postEndpoinstPath   = "POST /endpoints"
 	patchEndpointsPath  = "PATCH /endpoints/{id}"
 	deleteEndpointsPath = "DELETE /endpoints/{id}"
+	importEndpointsPath = "POST /endpoints/import"
+	exportEndpointsPath = "GET /endpoints/export"
+	getRequestsPath     = "GET /requests"
+	countRequestsPath   = "POST /requests/count"
+	deleteRequestsPath  = "DELETE /requests"
+	getProxyPath        = "GET /proxy"
+	putProxyPath        = "PUT /proxy"
+	getScenariosPath    = "GET /scenarios"
+	putScenarioPath     = "PUT /scenarios/{name}"
+	deleteScenariosPath = "DELETE /scenarios"
+	getNamespacesPath   = "GET /namespaces"
+	postNamespacesPath  = "POST /namespaces"
+	deleteNamespacePath = "DELETE /namespaces/{name}"
+	getMockFilesPath    = "GET /mockfiles"
+	getCAPath           = "GET /tls/ca"
 
 	errorMessage = `{"errors":[{"code":"%s", "detail":"%s"}]}`
 )
 
-func New(store *store.Store) http.Handler {
-	handle := &handlers{store, validator.New()}
+// Option configures the server returned by New.
+type Option func(*handlers)
+
+// WithJournal records the requests served by mock endpoints in j. By default
+// the server uses a new journal with the default limit.
+func WithJournal(j *journal.Journal) Option {
+	return func(h *handlers) {
+		h.journal = j
+	}
+}
+
+// Server is the handler of the mock endpoints, and of the API unless it's
+// served apart with NewSplit.
+type Server struct {
+	http.Handler
+	handlers *handlers
+}
+
+// Close stops the background work of the server, like the reaper and the
+// watch of the mocks directory, and waits for it to finish. The storage can
+// be closed afterwards.
+func (s *Server) Close() error {
+	s.handlers.cancel()
+	s.handlers.background.Wait()
+	return nil
+}
+
+// New returns the server, which serves the mock endpoints and the API. The API
+// is served under the prefix set by WithAPIPrefix, if any.
+func New(s store.Storage, opts ...Option) *Server {
+	handle := newHandlers(s, opts...)
+	if handle.apiPrefix == "" {
+		handle.api.HandleFunc("/", handle.all())
+		handle.start()
+		return &Server{Handler: withVndHeaderMiddleware(withNamespaceMiddleware(handle.api)), handlers: handle}
+	}
+	handle.api.HandleFunc("/", apiNotFound)
+	mux := http.NewServeMux()
+	mux.Handle(handle.apiPrefix+"/", http.StripPrefix(handle.apiPrefix, withNamespaceMiddleware(handle.api)))
+	mux.Handle("/", withNamespaceMiddleware(handle.all()))
+	handle.start()
+	return &Server{Handler: withVndHeaderMiddleware(mux), handlers: handle}
+}
+
+func newHandlers(s store.Storage, opts ...Option) *handlers {
+	handle := &handlers{Storage: s, Validate: store.NewValidator(), proxy: newProxy(), scenarios: newScenarios(), hits: newHits()}
+	for _, opt := range opts {
+		opt(handle)
+	}
+	if handle.journal == nil {
+		handle.journal = journal.New(journal.DefaultLimit)
+	}
+	handle.stop, handle.cancel = context.WithCancel(context.Background())
 	mux := http.NewServeMux()
 
-	mux.HandleFunc(getEndpointsPath, handle.fetchEndpoints())
-	mux.HandleFunc(postEndpoinstPath, handle.createEndpoint())
-	mux.HandleFunc(patchEndpointsPath, handle.updateEndpoint())
-	mux.HandleFunc(deleteEndpointsPath, handle.deleteEndpoint())
-	mux.HandleFunc("/", handle.all())
+	mux.HandleFunc(getEndpointsPath, handle.authorize(Viewer, namespace, handle.fetchEndpoints()))
+	mux.HandleFunc(postEndpoinstPath, handle.authorize(Editor, namespace, handle.createEndpoint()))
+	mux.HandleFunc(patchEndpointsPath, handle.authorize(Editor, namespace, handle.updateEndpoint()))
+	mux.HandleFunc(deleteEndpointsPath, handle.authorize(Editor, namespace, handle.deleteEndpoint()))
+	mux.HandleFunc(importEndpointsPath, handle.authorize(Editor, namespace, handle.importEndpoints()))
+	mux.HandleFunc(exportEndpointsPath, handle.authorize(Viewer, namespace, handle.exportEndpoints()))
+	mux.HandleFunc(getRequestsPath, handle.authorize(Viewer, namespace, handle.fetchRequests()))
+	mux.HandleFunc(countRequestsPath, handle.authorize(Viewer, namespace, handle.countRequests()))
+	mux.HandleFunc(deleteRequestsPath, handle.authorize(Editor, namespace, handle.deleteRequests()))
+	mux.HandleFunc(getProxyPath, handle.authorize(Viewer, serverWide, handle.fetchProxy()))
+	mux.HandleFunc(putProxyPath, handle.authorize(Admin, serverWide, handle.updateProxy()))
+	mux.HandleFunc(getScenariosPath, handle.authorize(Viewer, namespace, handle.fetchScenarios()))
+	mux.HandleFunc(putScenarioPath, handle.authorize(Editor, namespace, handle.updateScenario()))
+	mux.HandleFunc(deleteScenariosPath, handle.authorize(Editor, namespace, handle.resetScenarios()))
+	mux.HandleFunc(getNamespacesPath, handle.authorize(Viewer, anywhere, handle.fetchNamespaces()))
+	mux.HandleFunc(postNamespacesPath, handle.authorize(Admin, createdNamespace, handle.createNamespace()))
+	mux.HandleFunc(deleteNamespacePath, handle.authorize(Admin, namedNamespace, handle.deleteNamespace()))
+	mux.HandleFunc(getMockFilesPath, handle.authorize(Viewer, serverWide, handle.fetchMockFiles()))
+	mux.HandleFunc(getCAPath, handle.authorize(Viewer, anywhere, handle.fetchCA()))
+	handle.api = mux
 
-	return withVndHeaderMiddleware(mux)
+	return handle
+}
+
+// start loads the mocks directory and starts the background work once the
+// routes of the API are set, since they are reserved.
+func (h *handlers) start() {
+	if h.mocks != nil {
+		h.reloadMocks()
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			h.watchMocks()
+		}()
+	}
+	if h.reaper != nil {
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			h.reaper()
+		}()
+	}
 }
 
 func withVndHeaderMiddleware(next http.Handler) http.Handler {
@@ -41,18 +147,40 @@ func withVndHeaderMiddleware(next http.Handler) http.Handler {
 }
 
 type handlers struct {
-	*store.Store
+	store.Storage
 	*validator.Validate
+	journal   *journal.Journal
+	proxy     *proxy
+	scenarios *scenarios
+	hits      *hits
+	fixtures  string
+	reaper    func()
+	mocks     *mockDir
+	ca        []byte
+	auth      AuthConfig
+	api       *http.ServeMux
+	apiPrefix string
+	split     bool
+	// stop is done once the server is closed, and background waits for the
+	// goroutines that run until then.
+	stop       context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
 }
 
 func (h *handlers) fetchEndpoints() http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		e, err := h.FetchEndpoints()
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := h.endpoints(namespace(r))
 		if err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to fetch endpoints: %v", err))
 			return
 		}
-		if err := json.NewEncoder(w).Encode(e); err != nil {
+		now := time.Now()
+		list := &listedEndpoints{Data: make([]*listedEndpoint, 0, len(endpoints))}
+		for _, e := range endpoints {
+			list.Data = append(list.Data, &listedEndpoint{Endpoint: e, Meta: h.lifetime(e, now)})
+		}
+		if err := json.NewEncoder(w).Encode(list); err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error serializing endpoints: %v", err))
 			return
 		}
@@ -66,12 +194,12 @@ func (h *handlers) createEndpoint() http.HandlerFunc {
 			replyWithErr(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		ok, err := h.FindEndpoint(e.Attributes.Verb, e.Attributes.Path)
+		conflict, err := store.FindConflict(h, e)
 		if err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error finding endpoint: %v", err))
 			return
 		}
-		if ok != nil {
+		if conflict != nil {
 			replyWithErr(w, http.StatusConflict, fmt.Sprintf("the requested endpoint `%s %s` already exists", e.Attributes.Verb, e.Attributes.Path))
 			return
 		}
@@ -95,11 +223,24 @@ func (h *handlers) updateEndpoint() http.HandlerFunc {
 			replyWithErr(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		owned, err := h.owned(r, r.PathValue("id"))
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to update endpoint: %v", err))
+			return
+		}
+		if owned == nil {
+			replyWithErr(w, http.StatusNotFound, fmt.Sprintf("Requested Endpoint with ID `%s` does not exist", r.PathValue("id")))
+			return
+		}
+		if !editable(w, owned) {
+			return
+		}
 		updated, err := h.UpdateEndpoint(r.PathValue("id"), e)
 		if err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to update endpoint: %v", err))
 			return
 		}
+		h.forget(r.PathValue("id"))
 		if updated == nil {
 			replyWithErr(w, http.StatusNotFound, fmt.Sprintf("Requested Endpoint with ID `%s` does not exist", r.PathValue("id")))
 			return
@@ -114,12 +255,25 @@ func (h *handlers) updateEndpoint() http.HandlerFunc {
 
 func (h *handlers) deleteEndpoint() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		owned, err := h.owned(r, r.PathValue("id"))
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to delete endpoint: %v", err))
+			return
+		}
+		if owned == nil {
+			replyWithErr(w, http.StatusNotFound, fmt.Sprintf("Requested Endpoint with ID `%s` does not exist", r.PathValue("id")))
+			return
+		}
+		if !editable(w, owned) {
+			return
+		}
 		ok, err := h.DeleteEndpoint(r.PathValue("id"))
 		if err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("unable to delete endpoint: %v", err))
 			return
 		}
 		if ok {
+			h.forget(r.PathValue("id"))
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
@@ -129,16 +283,78 @@ func (h *handlers) deleteEndpoint() http.HandlerFunc {
 
 func (h *handlers) all() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		e, err := h.FindEndpoint(r.Method, r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			replyWithErr(w, http.StatusBadRequest, fmt.Sprintf("unable to read request body: %v", err))
+			return
+		}
+		req := &store.Request{
+			Namespace:  namespace(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.Query(),
+			Headers:    r.Header,
+			Body:       body,
+			Scenarios:  h.scenarios.snapshot(namespace(r)),
+			Time:       time.Now(),
+			ClientCert: clientCert(r),
+			Protocol:   r.Proto,
+		}
+		m, err := h.find(req)
 		if err != nil {
 			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error finding endpoint: %v", err))
 			return
 		}
-		if e == nil {
-			replyWithErr(w, http.StatusNotFound, fmt.Sprintf("Requested page `%s` does not exist", r.URL.Path))
+		h.record(r, body, m)
+		if m == nil {
+			if c := h.proxy.get(); c.Mode != Playback {
+				h.forward(w, r, body, c)
+				return
+			}
+			h.notFound(w, req)
+			return
+		}
+		res := h.scenarios.next(m.Endpoint)
+		if res.Template {
+			if res, err = render(res, newTemplateData(r, m.Params, body, req.Time)); err != nil {
+				replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error rendering response template: %v", err))
+				return
+			}
+		}
+		resBody, err := h.body(res)
+		if err != nil {
+			replyWithErr(w, http.StatusInternalServerError, fmt.Sprintf("error reading response body: %v", err))
+			return
+		}
+		if d := m.Endpoint.Attributes.Delay; d != nil {
+			wait(r.Context(), sampleDelay(d))
+		}
+		if f := m.Endpoint.Attributes.Fault; f != nil && fails(f) {
+			serveFault(w, res, resBody, f)
 			return
 		}
-		serve(w, e)
+		serve(w, res, resBody)
+	}
+}
+
+// find returns the endpoint that serves the request and counts the hit. The
+// search is repeated when the endpoint found has just served its last request.
+func (h *handlers) find(req *store.Request) (*store.Match, error) {
+	for {
+		m, err := h.FindEndpoint(req)
+		if err != nil || m == nil {
+			return m, err
+		}
+		ok, err := h.hits.take(m.Endpoint, func() error {
+			_, err := h.DeleteEndpoint(strconv.Itoa(m.Endpoint.ID))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return m, nil
+		}
 	}
 }
 
@@ -149,29 +365,56 @@ func (h *handlers) unmarshalAndVerify(r *http.Request) (*store.Endpoint, error)
 	if err := json.NewDecoder(r.Body).Decode(e); err != nil {
 		return nil, fmt.Errorf("Unable to decode request body: %v", err)
 	}
-	if err := h.Struct(e.Data); err != nil {
+	if e.Data != nil {
+		store.UnquoteJSONBodies(e.Data)
+	}
+	if err := h.verify(e.Data); err != nil {
 		return nil, err
 	}
+	e.Data.Attributes.Namespace = namespace(r)
+	e.Data.Attributes.Source = ""
+	e.Data.Attributes.SetExpiry(time.Now())
 	return e.Data, nil
 }
 
-func serve(w http.ResponseWriter, r *store.Response) {
-	// Remove application/vnd.api+json passed by middleware.
-	w.Header().Del("Content-Type")
+// verify returns why the endpoint can't be served, if it can't.
+func (h *handlers) verify(e *store.Endpoint) error {
+	if err := h.Struct(e); err != nil {
+		return err
+	}
+	if err := h.checkReserved(e); err != nil {
+		return err
+	}
+	for _, res := range e.Attributes.Responses() {
+		if res.Template {
+			if err := parseTemplates(res); err != nil {
+				return err
+			}
+		}
+	}
+	return h.checkFixtures(e)
+}
 
-	r.Body = strings.TrimPrefix(r.Body, "\"")
-	r.Body = strings.TrimSuffix(r.Body, "\"")
-	for k, v := range r.Headers {
-		w.Header().Add(k, v)
+// editable reports whether the endpoint can be changed through the API, and
+// replies with a conflict when it's defined in a file of the mocks directory.
+func editable(w http.ResponseWriter, e *store.Endpoint) bool {
+	if src := e.Attributes.Source; src != "" {
+		replyWithErr(w, http.StatusConflict, fmt.Sprintf("Endpoint with ID `%d` is defined in `%s`, change the file instead", e.ID, src))
+		return false
 	}
-	w.WriteHeader(r.Code)
+	return true
+}
 
-	fmt.Fprint(w, r.Body)
+func serve(w http.ResponseWriter, r *store.Response, body []byte) {
+	writeHeader(w, r, body)
+	if _, err := w.Write(body); err != nil {
+		slog.Warn("error writing response", "err", err)
+	}
 }
 
 func replyWithErr(w http.ResponseWriter, code int, err string) {
 	if code == http.StatusInternalServerError {
-		log.Printf("internal error: %s", err)
+		slog.Error("internal error", "err", err)
 		err = "Something went horribly wrong :("
 	}
 	w.WriteHeader(code)
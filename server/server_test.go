@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,9 +15,10 @@ import (
 
 const (
 	example         = `{"data":{"type":"endpoints",%s"attributes":{"verb":"GET","path":"/greeting","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"\"{ \"message\": \"Hello, world\" }\""}}}}`
+	exampleCreated  = `{"data":{"type":"endpoints","id":1,"attributes":{"verb":"GET","path":"/greeting","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"","json":{"message":"Hello, world"}}}}}`
 	exampleRename   = `{"data":{"type":"endpoints",%s"attributes":{"verb":"GET","path":"/post_it","response":{"code":201,"headers":{"Accept":"test/plain","x-api-key":"superdupersecret"},"body":"Your secrets are not so safe"}}}}`
 	exampleError    = `{"data":{"type":"endpoints","attributes":{"verb":"GETS","path":"/greeting","response":{"code":200,"headers":{},"body":"\"{ \"message\": \"Hello, world\" }\""}}}}`
-	expectedSeeded  = `{"data":[{"type":"endpoints","id":1,"attributes":{"verb":"GET","path":"/revert_entropy","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"\"{ \"message\": \"INSUFFICIENT DATA FOR MEANINGFUL ANSWER\" }\""}}},{"type":"endpoints","id":2,"attributes":{"verb":"POST","path":"/post_it","response":{"code":201,"headers":{"Accept":"test/plain","x-api-key":"superdupersecret"},"body":"Your secrets are not so safe"}}},{"type":"endpoints","id":3,"attributes":{"verb":"PUT","path":"/fail","response":{"code":400,"headers":{"Accept":"test/plain","Content-Type":"application/json"},"body":"\"{\"error\": \"something went horribly wrong :(\" }\""}}},{"type":"endpoints","id":4,"attributes":{"verb":"DELETE","path":"/fake_delete","response":{"code":204,"headers":{},"body":""}}}]}`
+	expectedSeeded  = `{"data":[{"type":"endpoints","id":1,"attributes":{"verb":"GET","path":"/revert_entropy","response":{"code":200,"headers":{"Content-Type":"application/json"},"body":"","json":{"message":"INSUFFICIENT DATA FOR MEANINGFUL ANSWER"}}}},{"type":"endpoints","id":2,"attributes":{"verb":"POST","path":"/post_it","response":{"code":201,"headers":{"Accept":"test/plain","x-api-key":"superdupersecret"},"body":"Your secrets are not so safe"}}},{"type":"endpoints","id":3,"attributes":{"verb":"PUT","path":"/fail","response":{"code":400,"headers":{"Accept":"test/plain","Content-Type":"application/json"},"body":"","json":{"error":"something went horribly wrong :("}}}},{"type":"endpoints","id":4,"attributes":{"verb":"DELETE","path":"/fake_delete","response":{"code":204,"headers":{},"body":""}}}]}`
 	exampleExisting = `{"data":{"type":"endpoints","attributes":{"verb":"DELETE","path":"/fake_delete","response":{"code":204,"headers":{},"body":""}}}}`
 )
 
@@ -71,7 +73,7 @@ func TestServer(t *testing.T) {
 			requestBody:    fmt.Sprintf(example, ""),
 			wantResCode:    http.StatusCreated,
 			wantResHeaders: map[string]string{"Content-Type": "application/vnd.api+json"},
-			wantResBody:    fmt.Sprintf(example, `"id":1,`),
+			wantResBody:    exampleCreated,
 		},
 		{
 			name:           "POST /endpoints on an already created endpoint returns 409",
@@ -83,6 +85,16 @@ func TestServer(t *testing.T) {
 			wantResHeaders: map[string]string{"Content-Type": "application/vnd.api+json"},
 			wantResBody:    "{\"errors\":[{\"code\":\"Conflict\", \"detail\":\"the requested endpoint `DELETE /fake_delete` already exists\"}]}",
 		},
+		{
+			name:           "POST /endpoints with a path template overlapping an existing path creates it",
+			seed:           true,
+			requestMethod:  http.MethodPost,
+			requestPath:    "/endpoints",
+			requestBody:    `{"data":{"type":"endpoints","attributes":{"verb":"DELETE","path":"/{name}","response":{"code":204}}}}`,
+			wantResCode:    http.StatusCreated,
+			wantResHeaders: map[string]string{"Content-Type": "application/vnd.api+json"},
+			wantResBody:    `{"data":{"type":"endpoints","id":5,"attributes":{"verb":"DELETE","path":"/{name}","response":{"code":204,"headers":null,"body":""}}}}`,
+		},
 		{
 			name:           "PATCH /endpoints/{id} updates the existing endpoint",
 			seed:           true,
@@ -125,7 +137,7 @@ func TestServer(t *testing.T) {
 			requestPath:    "/revert_entropy",
 			wantResCode:    http.StatusOK,
 			wantResHeaders: map[string]string{"Content-Type": "application/json"},
-			wantResBody:    "{ \"message\": \"INSUFFICIENT DATA FOR MEANINGFUL ANSWER\" }",
+			wantResBody:    `{"message":"INSUFFICIENT DATA FOR MEANINGFUL ANSWER"}`,
 		},
 		{
 			name:           "Creating an endpoitn with incorrect information returns an error",
@@ -140,7 +152,7 @@ func TestServer(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			store, err := store.New()
+			store, err := store.New("")
 			assert.NoError(t, err)
 			defer store.Close()
 
@@ -168,3 +180,71 @@ func TestServer(t *testing.T) {
 		})
 	}
 }
+
+// failingStorage is a fake store.Storage whose operations always fail.
+type failingStorage struct {
+	store.Storage
+}
+
+func (failingStorage) FetchEndpoints() (*store.Many, error) {
+	return nil, errors.New("storage is down")
+}
+
+func (failingStorage) FindEndpoint(*store.Request) (*store.Match, error) {
+	return nil, errors.New("storage is down")
+}
+
+func TestServerStorageErrors(t *testing.T) {
+	server := httptest.NewServer(New(failingStorage{}))
+	defer server.Close()
+
+	for _, path := range []string{"/endpoints", "/hello"} {
+		t.Run("GET "+path+" hides the storage error", func(t *testing.T) {
+			res, err := http.Get(server.URL + path)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+			assert.Equal(t, `{"errors":[{"code":"Internal Server Error", "detail":"Something went horribly wrong :("}]}`, string(body))
+		})
+	}
+}
+
+func TestRequestMatching(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/search","match":{"query":{"q":"foo"}},"response":{"code":200,"body":"foo results"}}}}`)
+	mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/search","match":{"query":{"q":"bar"}},"response":{"code":200,"body":"bar results"}}}}`)
+
+	for _, q := range []string{"foo", "bar"} {
+		t.Run("GET /search?q="+q, func(t *testing.T) {
+			res, err := http.Get(server.URL + "/search?q=" + q)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, q+" results", string(body))
+		})
+	}
+
+	t.Run("creating an endpoint with the same matchers returns 409", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/endpoints", "application/vnd.api+json", strings.NewReader(
+			`{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/search","match":{"query":{"q":{"equals":"foo"}}},"response":{"code":200}}}}`,
+		))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+}
+
+func mustCreateEndpoint(t testing.TB, url, body string) {
+	t.Helper()
+	res, err := http.Post(url+"/endpoints", "application/vnd.api+json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Serve listens on the address of every server and serves until ctx is done
+// or one of them fails. Servers with a TLS config serve HTTPS with its
+// certificates. The servers then stop accepting connections and wait
+// up to grace for the requests in flight to finish, so their storage writes
+// and journal entries are complete. Requests still running after that, like
+// those delayed by latency injection, are cancelled and their connections
+// closed.
+func Serve(ctx context.Context, grace time.Duration, servers ...*http.Server) error {
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, srv := range servers {
+		l, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close() //nolint:errcheck // the listen error is what matters
+			}
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	failed := make(chan error, len(servers))
+	for i, srv := range servers {
+		srv.BaseContext = func(net.Listener) context.Context { return requests }
+		go func() {
+			serve := srv.Serve
+			if srv.TLSConfig != nil {
+				serve = func(l net.Listener) error { return srv.ServeTLS(l, "", "") }
+			}
+			if err := serve(listeners[i]); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down", "grace", grace)
+	case err = <-failed:
+	}
+
+	drain, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	stop := context.AfterFunc(drain, cancelRequests)
+	defer stop()
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(drain); err != nil {
+				slog.Warn("requests cancelled on shutdown", "addr", srv.Addr, "err", err)
+				srv.Close() //nolint:errcheck // the connections are closed anyway
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
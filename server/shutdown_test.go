@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServe(t *testing.T) {
+	t.Run("requests in flight finish", func(t *testing.T) {
+		started := make(chan struct{})
+		srv := &http.Server{Addr: freeAddr(t), Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusTeapot)
+		})}
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error)
+		go func() { served <- Serve(ctx, time.Second, srv) }()
+
+		waitListening(t, srv.Addr)
+		res := make(chan *http.Response)
+		go func() {
+			r, err := http.Get("http://" + srv.Addr)
+			assert.NoError(t, err)
+			res <- r
+		}()
+		<-started
+		cancel()
+
+		r := <-res
+		r.Body.Close()
+		assert.Equal(t, http.StatusTeapot, r.StatusCode)
+		assert.NoError(t, <-served)
+		_, err := http.Get("http://" + srv.Addr)
+		assert.Error(t, err)
+	})
+
+	t.Run("requests are cancelled after the grace period", func(t *testing.T) {
+		started := make(chan struct{})
+		cancelled := make(chan struct{})
+		srv := &http.Server{Addr: freeAddr(t), Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+			close(cancelled)
+		})}
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error)
+		go func() { served <- Serve(ctx, 50*time.Millisecond, srv) }()
+
+		waitListening(t, srv.Addr)
+		go http.Get("http://" + srv.Addr) //nolint:errcheck,bodyclose // the connection is closed on shutdown
+		<-started
+		cancel()
+
+		select {
+		case err := <-served:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Serve didn't return after the grace period")
+		}
+		<-cancelled
+	})
+
+	t.Run("listen errors are returned", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer l.Close()
+		err = Serve(context.Background(), time.Second, &http.Server{Addr: freeAddr(t)}, &http.Server{Addr: l.Addr().String()})
+		assert.ErrorContains(t, err, "address already in use")
+	})
+}
+
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		c, err := net.Dial("tcp", addr)
+		if err == nil {
+			c.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerClose(t *testing.T) {
+	s := store.NewMemory()
+	srv := New(s, WithReaper(context.Background(), 10*time.Millisecond))
+	assert.NoError(t, srv.Close())
+
+	expired := time.Now().Add(-time.Minute)
+	e := &store.Endpoint{Type: "endpoints", Attributes: store.Attributes{
+		Verb: http.MethodGet, Path: "/expired", ExpiresAt: &expired, Response: store.Response{Code: http.StatusOK},
+	}}
+	_, err := s.CreateEndpoint(e)
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	all, err := s.FetchEndpoints()
+	assert.NoError(t, err)
+	assert.Len(t, all.Data, 1, "the reaper stopped")
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+)
+
+// templateFuncs are the functions available to response templates on top of
+// the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"uuid": newUUID,
+	"now":  time.Now,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// templateData is the incoming request as seen by response templates, e.g.
+// `{{.Path.id}}`, `{{.Query.page}}` or `{{.Body.user.name}}`.
+type templateData struct {
+	Method    string
+	Path      map[string]string
+	Query     map[string]string
+	Headers   map[string]string
+	Body      any
+	RawBody   string
+	Timestamp string
+}
+
+// newTemplateData returns the data of the request, received at the given
+// time, for response templates.
+func newTemplateData(r *http.Request, params map[string]string, body []byte, received time.Time) *templateData {
+	d := &templateData{
+		Method:    r.Method,
+		Path:      params,
+		Query:     map[string]string{},
+		Headers:   map[string]string{},
+		RawBody:   string(body),
+		Timestamp: received.UTC().Format(time.RFC3339),
+	}
+	for k := range r.URL.Query() {
+		d.Query[k] = r.URL.Query().Get(k)
+	}
+	for k := range r.Header {
+		d.Headers[k] = r.Header.Get(k)
+	}
+	// Bodies that are not JSON are still available as RawBody, and their
+	// fields render empty like those missing from JSON bodies.
+	if err := json.Unmarshal(body, &d.Body); err != nil || d.Body == nil {
+		d.Body = map[string]any{}
+	}
+
+	return d
+}
+
+// parseTemplates checks that the body, headers and cookie values of a
+// templated response are valid templates.
+func parseTemplates(r *store.Response) error {
+	if _, err := parseTemplate(r.Body); err != nil {
+		return fmt.Errorf("invalid body template: %v", err)
+	}
+	for k, values := range r.Headers {
+		for _, v := range values {
+			if _, err := parseTemplate(v); err != nil {
+				return fmt.Errorf("invalid template for header %s: %v", k, err)
+			}
+		}
+	}
+	for _, c := range r.Cookies {
+		if _, err := parseTemplate(c.Value); err != nil {
+			return fmt.Errorf("invalid template for cookie %s: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// render returns a copy of the response with its body, headers and cookie
+// values rendered using the request data.
+func render(r *store.Response, data *templateData) (*store.Response, error) {
+	rendered := *r
+	body, err := execute(r.Body, data)
+	if err != nil {
+		return nil, err
+	}
+	rendered.Body = body
+	rendered.Headers = make(store.Headers, len(r.Headers))
+	for k, values := range r.Headers {
+		rendered.Headers[k] = make([]string, len(values))
+		for i, v := range values {
+			if rendered.Headers[k][i], err = execute(v, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	rendered.Cookies = make([]store.Cookie, len(r.Cookies))
+	for i, c := range r.Cookies {
+		if c.Value, err = execute(c.Value, data); err != nil {
+			return nil, err
+		}
+		rendered.Cookies[i] = c
+	}
+	return &rendered, nil
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+func execute(text string, data *templateData) (string, error) {
+	t, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	filled := *data
+	filled.Body = fillBody(data.Body, bodyFields(t.Root))
+	var b strings.Builder
+	if err := t.Execute(&b, &filled); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// bodyFields returns the paths of the body fields read by the template, like
+// [user name] for `{{.Body.user.name}}`.
+func bodyFields(node parse.Node) [][]string {
+	var fields [][]string
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.IfNode:
+			walk(&n.BranchNode)
+		case *parse.RangeNode:
+			walk(&n.BranchNode)
+		case *parse.WithNode:
+			walk(&n.BranchNode)
+		case *parse.BranchNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.FieldNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "Body" {
+				fields = append(fields, n.Ident[1:])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 2 && n.Ident[0] == "$" && n.Ident[1] == "Body" {
+				fields = append(fields, n.Ident[2:])
+			}
+		}
+	}
+	walk(node)
+	return fields
+}
+
+// fillBody returns a copy of the body with the fields missing along the paths
+// set, empty objects for those that hold others and empty strings for the
+// rest, so that templates render them empty instead of failing on nested
+// fields of missing ones. Fields that are present are left as they are.
+func fillBody(body any, paths [][]string) any {
+	// Longer paths go first, so that fields holding others become objects.
+	slices.SortFunc(paths, func(a, b []string) int { return len(b) - len(a) })
+	for _, path := range paths {
+		body = fillPath(body, path)
+	}
+	return body
+}
+
+func fillPath(v any, path []string) any {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		if v != nil {
+			return v
+		}
+		obj = map[string]any{}
+	}
+	filled := maps.Clone(obj)
+	child, ok := filled[path[0]]
+	switch {
+	case len(path) > 1:
+		filled[path[0]] = fillPath(child, path[1:])
+	case !ok:
+		filled[path[0]] = ""
+	}
+	return filled
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/users/42?page=2", strings.NewReader(`{"user":{"name":"Susan"}}`))
+	r.Header.Set("X-Request-Id", "abc")
+	received := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := newTemplateData(r, map[string]string{"id": "42"}, []byte(`{"user":{"name":"Susan"}}`), received)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "path params", text: `{"id": "{{.Path.id}}"}`, want: `{"id": "42"}`},
+		{name: "query params", text: `{{.Query.page}}`, want: "2"},
+		{name: "headers", text: `{{index .Headers "X-Request-Id"}}`, want: "abc"},
+		{name: "JSON body fields", text: `{{.Body.user.name}}`, want: "Susan"},
+		{name: "raw body", text: `{{.RawBody}}`, want: `{"user":{"name":"Susan"}}`},
+		{name: "method", text: `{{.Method}}`, want: http.MethodPost},
+		{name: "json function", text: `{{json .Body.user}}`, want: `{"name":"Susan"}`},
+		{name: "missing keys render empty", text: `[{{.Path.nope}}]`, want: "[]"},
+		{name: "missing body fields render empty", text: `[{{.Body.user.age}}{{.Body.a.b.c}}{{with .Body.user}}{{.name}}{{end}}]`, want: "[Susan]"},
+		{name: "missing body fields are false", text: `{{if .Body.a.b}}yes{{else}}no{{end}}`, want: "no"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := render(&store.Response{Body: test.text, Headers: store.Headers{"X-Echo": {test.text}}}, data)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, res.Body)
+			assert.Equal(t, test.want, res.Headers.Get("X-Echo"))
+		})
+	}
+
+	t.Run("timestamp and uuid", func(t *testing.T) {
+		res, err := render(&store.Response{Body: `{{.Timestamp}} {{uuid}}`}, data)
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^2026-01-02T03:04:05Z [0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), res.Body)
+	})
+
+	t.Run("bodies that aren't JSON", func(t *testing.T) {
+		for _, body := range []string{"name=Susan", ""} {
+			data := newTemplateData(r, nil, []byte(body), received)
+			res, err := render(&store.Response{Body: `[{{.Body.user.name}}{{.Body.x}}] {{.RawBody}}`}, data)
+			assert.NoError(t, err)
+			assert.Equal(t, "[] "+body, res.Body)
+		}
+	})
+}
+
+func TestTemplatedEndpoint(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemory()))
+	defer server.Close()
+
+	t.Run("invalid templates are rejected", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/endpoints", "application/vnd.api+json", strings.NewReader(
+			`{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/users/{id}","response":{"code":200,"template":true,"body":"{{.Path.id"}}}}`,
+		))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("templated responses echo the request", func(t *testing.T) {
+		mustCreateEndpoint(t, server.URL, `{"data":{"type":"endpoints","attributes":{"verb":"GET","path":"/users/{id}","response":{"code":200,"template":true,"headers":{"Location":"/users/{{.Path.id}}"},"body":"{\"id\": \"{{.Path.id}}\"}"}}}}`)
+
+		res, err := http.Get(server.URL + "/users/42")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "/users/42", res.Header.Get("Location"))
+		assert.Equal(t, `{"id": "42"}`, string(body))
+	})
+}
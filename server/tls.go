@@ -0,0 +1,33 @@
+package server
+
+import "net/http"
+
+// WithCA serves the PEM encoded certificate of the CA that issues the server
+// certificates on GET /tls/ca, so clients can add it to their trust stores.
+func WithCA(cert []byte) Option {
+	return func(h *handlers) {
+		h.ca = cert
+	}
+}
+
+// fetchCA writes the certificate of the CA. Any client of the API can fetch
+// it, since it's public.
+func (h *handlers) fetchCA() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if h.ca == nil {
+			replyWithErr(w, http.StatusNotFound, "there's no CA: certificates are only generated with automatic TLS")
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Write(h.ca) //nolint:errcheck // nothing to do if the client went away
+	}
+}
+
+// clientCert returns the subject of the verified certificate the client
+// presented, or empty when there's none.
+func clientCert(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.String()
+}
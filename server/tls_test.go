@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alvaroalonsobabbel/echo/certs"
+	"github.com/Alvaroalonsobabbel/echo/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLS(t *testing.T) {
+	ca, err := certs.NewCA()
+	assert.NoError(t, err)
+	client := clientCertificate(t, "ci")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(client.Leaf)
+
+	srv := New(store.NewMemory(), WithCA(ca.PEM()))
+	defer srv.Close()
+	httpSrv := &http.Server{Addr: freeAddr(t), Handler: srv, TLSConfig: &tls.Config{
+		GetCertificate: ca.GetCertificate,
+		ClientCAs:      clientCAs,
+		ClientAuth:     tls.VerifyClientCertIfGiven,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- Serve(ctx, time.Second, httpSrv) }()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-served)
+	}()
+	waitListening(t, httpSrv.Addr)
+	url := "https://" + httpSrv.Addr
+
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(ca.PEM()))
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs: roots, Certificates: []tls.Certificate{client},
+	}}}
+
+	t.Run("the CA is served for trust stores", func(t *testing.T) {
+		res, err := anonymous.Get(url + "/tls/ca")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/x-pem-file", res.Header.Get("Content-Type"))
+		assert.Equal(t, ca.PEM(), body)
+	})
+
+	t.Run("endpoints match the client certificate", func(t *testing.T) {
+		res, err := anonymous.Post(url+"/endpoints", "application/json", strings.NewReader(`{"data":{"type":"endpoints","attributes":{
+			"verb":"GET","path":"/whoami","match":{"clientCert":{"matches":"^CN=ci"}},"response":{"code":200,"body":"ci"}
+		}}}`))
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		res, err = authenticated.Get(url + "/whoami")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res, err = anonymous.Get(url + "/whoami")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("there's no CA without automatic TLS", func(t *testing.T) {
+		server := httptest.NewServer(New(store.NewMemory()))
+		defer server.Close()
+		res, body := do(t, http.MethodGet, server.URL+"/tls/ca", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Contains(t, body, "there's no CA")
+	})
+}
+
+// clientCertificate returns a self-signed client certificate with the common
+// name.
+func clientCertificate(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"Acme"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Encoding sets how the body of a response is written.
+type Encoding string
+
+const (
+	// TextEncoding serves the body as it is.
+	TextEncoding Encoding = "text"
+	// JSONEncoding serves the body, which must be JSON, as it is.
+	JSONEncoding Encoding = "json"
+	// Base64Encoding decodes the body, for binary payloads.
+	Base64Encoding Encoding = "base64"
+)
+
+// JSONValue is a JSON document served as a response body. It's kept in its
+// compact form, so equal documents are always stored and served the same way.
+type JSONValue []byte
+
+func (j JSONValue) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSONValue) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*j = nil
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return err
+	}
+	*j = buf.Bytes()
+	return nil
+}
+
+// UnquoteBodies converts the responses of an endpoint saved by versions that
+// removed the surrounding quotes of bodies when serving them, so that it keeps
+// serving the same content: JSON documents wrapped in quotes become JSON
+// values and other bodies lose their surrounding quotes.
+func UnquoteBodies(e *Endpoint) {
+	for _, r := range e.Attributes.Responses() {
+		if !unquoteJSON(r) && r.Encoding == "" && r.File == "" && len(r.JSON) == 0 {
+			r.Body = strings.TrimSuffix(strings.TrimPrefix(r.Body, `"`), `"`)
+		}
+	}
+}
+
+// UnquoteJSONBodies converts the bodies that are JSON documents wrapped in
+// quotes, like `"{ "key": "value" }"`, to JSON values. It keeps clients written
+// for versions that removed the surrounding quotes of bodies working. Bodies
+// with an encoding are left as they are, so the `text` encoding serves such a
+// body quotes included.
+func UnquoteJSONBodies(e *Endpoint) {
+	for _, r := range e.Attributes.Responses() {
+		unquoteJSON(r)
+	}
+}
+
+func unquoteJSON(r *Response) bool {
+	if r.Encoding != "" || r.File != "" || r.Template || len(r.JSON) > 0 ||
+		len(r.Body) < 2 || !strings.HasPrefix(r.Body, `"`) || !strings.HasSuffix(r.Body, `"`) {
+		return false
+	}
+	inner := strings.TrimSpace(r.Body[1 : len(r.Body)-1])
+	if !strings.HasPrefix(inner, "{") && !strings.HasPrefix(inner, "[") {
+		return false
+	}
+	var j JSONValue
+	if err := j.UnmarshalJSON([]byte(inner)); err != nil {
+		return false
+	}
+	r.JSON, r.Body = j, ""
+	return true
+}
+
+// validateFixture checks that fixture files are relative paths that don't
+// leave the fixtures directory.
+func validateFixture(fl validator.FieldLevel) bool {
+	return filepath.IsLocal(filepath.FromSlash(fl.Field().String()))
+}
+
+// validateResponse checks that the response has a single body, that it can
+// be served with its encoding and that its cookies can be written. Templated
+// JSON bodies and cookie values are only checked once rendered.
+func validateResponse(sl validator.StructLevel) {
+	r := sl.Current().Interface().(Response)
+	if r.File != "" && r.Body != "" {
+		sl.ReportError(r.File, "File", "File", "excluded_with", "Body")
+	}
+	if len(r.JSON) > 0 && (r.Body != "" || r.File != "" || r.Template) {
+		sl.ReportError(r.JSON, "JSON", "JSON", "excluded_with", "Body File Template")
+	}
+	for _, c := range r.Cookies {
+		if r.Template {
+			c.Value = ""
+		}
+		if err := c.HTTP().Valid(); err != nil {
+			sl.ReportError(r.Cookies, "Cookies", "Cookies", "cookie", err.Error())
+		}
+	}
+	switch r.Encoding {
+	case JSONEncoding:
+		if !r.Template && r.Body != "" && !json.Valid([]byte(r.Body)) {
+			sl.ReportError(r.Body, "Body", "Body", "json", "")
+		}
+	case Base64Encoding:
+		if _, err := base64.StdEncoding.DecodeString(r.Body); err != nil {
+			sl.ReportError(r.Body, "Body", "Body", "base64", "")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONValue(t *testing.T) {
+	r := Response{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"json": { "a": [1, 2] }}`), &r))
+	assert.Equal(t, JSONValue(`{"a":[1,2]}`), r.JSON)
+
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"json":{"a":[1,2]}`)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"json": null}`), &r))
+	assert.Nil(t, r.JSON)
+	assert.Error(t, json.Unmarshal([]byte(`{"json": {]}`), &r))
+}
+
+func TestUnquoteBodies(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  Response
+		want      Response
+		wantQuick Response
+	}{
+		{
+			name:      "quoted JSON objects become JSON",
+			response:  Response{Body: `"{ "a": 1 }"`},
+			want:      Response{JSON: JSONValue(`{"a":1}`)},
+			wantQuick: Response{JSON: JSONValue(`{"a":1}`)},
+		},
+		{
+			name:      "quoted text loses its quotes",
+			response:  Response{Body: `"hi"`},
+			want:      Response{Body: "hi"},
+			wantQuick: Response{Body: `"hi"`},
+		},
+		{
+			name:      "quoted JSON strings only lose their quotes",
+			response:  Response{Body: `"\"hi\""`},
+			want:      Response{Body: `\"hi\"`},
+			wantQuick: Response{Body: `"\"hi\""`},
+		},
+		{
+			name:      "templates are left as they are",
+			response:  Response{Body: `"{ "a": {{.Path.id}} }"`, Template: true},
+			want:      Response{Body: `{ "a": {{.Path.id}} }`, Template: true},
+			wantQuick: Response{Body: `"{ "a": {{.Path.id}} }"`, Template: true},
+		},
+		{
+			name:      "bodies with an encoding are left as they are",
+			response:  Response{Body: `"{}"`, Encoding: TextEncoding},
+			want:      Response{Body: `"{}"`, Encoding: TextEncoding},
+			wantQuick: Response{Body: `"{}"`, Encoding: TextEncoding},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &Endpoint{Attributes: Attributes{Response: test.response}}
+			UnquoteBodies(e)
+			assert.Equal(t, test.want, e.Attributes.Response)
+			sequence := &Endpoint{Attributes: Attributes{Sequence: &Sequence{Responses: []Response{test.response}}}}
+			UnquoteBodies(sequence)
+			assert.Equal(t, test.want, sequence.Attributes.Sequence.Responses[0])
+
+			e.Attributes.Response = test.response
+			UnquoteJSONBodies(e)
+			assert.Equal(t, test.wantQuick, e.Attributes.Response)
+		})
+	}
+}
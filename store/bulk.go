@@ -0,0 +1,56 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// FindConflict returns the stored endpoint with the same route as e in its
+// namespace, if any. Expired endpoints, which are about to be deleted, don't
+// conflict.
+func FindConflict(s Storage, e *Endpoint) (*Endpoint, error) {
+	existing, err := s.FetchEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, ee := range existing.Data {
+		if ee.Attributes.Namespace == e.Attributes.Namespace && ee.Attributes.Route() == e.Attributes.Route() &&
+			!ee.Attributes.Expired(now) {
+			return ee, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateEndpoints validates and creates the given endpoints, skipping the
+// invalid ones and those that conflict with an existing endpoint. It returns
+// the created endpoints and the reason why each of the others was skipped.
+// The error is only set when the storage fails.
+func CreateEndpoints(s Storage, endpoints []*Endpoint) ([]*Endpoint, []error, error) {
+	validate := NewValidator()
+	created := []*Endpoint{}
+	var skipped []error
+	for _, e := range endpoints {
+		name := fmt.Sprintf("%s %s", e.Attributes.Verb, e.Attributes.Path)
+		if err := validate.Struct(e); err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		e.Attributes.SetExpiry(time.Now())
+		conflict, err := FindConflict(s, e)
+		if err != nil {
+			return nil, nil, err
+		}
+		if conflict != nil {
+			skipped = append(skipped, fmt.Errorf("%s: conflicts with endpoint %d", name, conflict.ID))
+			continue
+		}
+		c, err := s.CreateEndpoint(e)
+		if err != nil {
+			return nil, nil, err
+		}
+		created = append(created, c.Data)
+	}
+	return created, skipped, nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEndpoints(t *testing.T) {
+	s := NewMemory()
+	existing, err := s.CreateEndpoint(newTestEndpoint())
+	assert.NoError(t, err)
+
+	valid := newTestEndpoint()
+	valid.Attributes.Path = "/valid"
+	invalid := newTestEndpoint()
+	invalid.Attributes.Response.Code = 0
+
+	created, skipped, err := CreateEndpoints(s, []*Endpoint{newTestEndpoint(), valid, invalid})
+	assert.NoError(t, err)
+	assert.Len(t, created, 1)
+	assert.Equal(t, valid.Attributes, created[0].Attributes)
+	assert.Len(t, skipped, 2)
+	assert.EqualError(t, skipped[0], "GET /hello: conflicts with endpoint 1")
+	assert.ErrorContains(t, skipped[1], "GET /hello: Key: 'Endpoint.Attributes.Response.Code'")
+
+	conflict, err := FindConflict(s, newTestEndpoint())
+	assert.NoError(t, err)
+	assert.Equal(t, existing.Data, conflict)
+
+	t.Run("endpoints in other namespaces don't conflict", func(t *testing.T) {
+		e := newTestEndpoint()
+		e.Attributes.Namespace = "ci-1"
+		conflict, err := FindConflict(s, e)
+		assert.NoError(t, err)
+		assert.Nil(t, conflict)
+	})
+
+	t.Run("expired endpoints don't conflict", func(t *testing.T) {
+		expired := newTestEndpoint()
+		expired.Attributes.Path = "/expired"
+		expired.Attributes.ExpiresAt = &time.Time{}
+		_, err := s.CreateEndpoint(expired)
+		assert.NoError(t, err)
+
+		ttl := newTestEndpoint()
+		ttl.Attributes.Path, ttl.Attributes.TTL = "/expired", 60
+		created, skipped, err := CreateEndpoints(s, []*Endpoint{ttl})
+		assert.NoError(t, err)
+		assert.Empty(t, skipped)
+		assert.Len(t, created, 1)
+		assert.Zero(t, created[0].Attributes.TTL)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), *created[0].Attributes.ExpiresAt, 5*time.Second)
+	})
+}
@@ -0,0 +1,58 @@
+package store
+
+// Distribution is the probability distribution of a delay.
+type Distribution string
+
+const (
+	// Fixed delays every response by Milliseconds.
+	Fixed Distribution = "fixed"
+	// Uniform delays responses between Min and Max milliseconds.
+	Uniform Distribution = "uniform"
+	// Normal delays responses around Mean milliseconds, with a standard
+	// deviation of StdDev. Negative samples are served without delay.
+	Normal Distribution = "normal"
+	// LogNormal delays responses around Median milliseconds with a long tail,
+	// whose weight is given by Sigma. It's the usual shape of real latencies.
+	LogNormal Distribution = "lognormal"
+)
+
+// Delay is the time an endpoint waits before replying. All the durations are
+// given in milliseconds.
+type Delay struct {
+	Distribution Distribution `json:"distribution" validate:"required,oneof=fixed uniform normal lognormal"`
+	Milliseconds int          `json:"milliseconds,omitempty" validate:"required_if=Distribution fixed,gte=0"`
+	Min          int          `json:"min,omitempty" validate:"gte=0"`
+	Max          int          `json:"max,omitempty" validate:"required_if=Distribution uniform,gtefield=Min"`
+	Mean         int          `json:"mean,omitempty" validate:"required_if=Distribution normal,gte=0"`
+	StdDev       int          `json:"stdDev,omitempty" validate:"gte=0"`
+	Median       int          `json:"median,omitempty" validate:"required_if=Distribution lognormal,gte=0"`
+	Sigma        float64      `json:"sigma,omitempty" validate:"gte=0"`
+}
+
+// FaultType is the way a faulty endpoint fails.
+type FaultType string
+
+const (
+	// ErrorFault replies with an error status code and no body.
+	ErrorFault FaultType = "error"
+	// CloseFault resets the connection without replying.
+	CloseFault FaultType = "close"
+	// EmptyFault closes the connection without replying.
+	EmptyFault FaultType = "empty"
+	// TruncateFault sends the headers and half of the body, then closes the
+	// connection.
+	TruncateFault FaultType = "truncate"
+	// ThrottleFault sends the response at BytesPerSecond.
+	ThrottleFault FaultType = "throttle"
+)
+
+// Fault makes an endpoint fail some of the requests it serves.
+type Fault struct {
+	Type FaultType `json:"type" validate:"required,oneof=error close empty truncate throttle"`
+	// Probability of a request failing, from 0 to 1. Every request fails when
+	// it's not set.
+	Probability *float64 `json:"probability,omitempty" validate:"omitempty,gte=0,lte=1"`
+	// Code is the status code of error faults. It defaults to 500.
+	Code           int `json:"code,omitempty" validate:"omitempty,gte=100,lte=599"`
+	BytesPerSecond int `json:"bytesPerSecond,omitempty" validate:"required_if=Type throttle,gte=0"`
+}
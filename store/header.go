@@ -0,0 +1,116 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Headers are the headers of a response. A header can have several values,
+// like Vary or Link, given as a list of strings, or a single one given as a
+// string.
+type Headers map[string][]string
+
+// MarshalJSON writes headers with a single value as a string, the way they
+// were always written.
+func (h Headers) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("null"), nil
+	}
+	m := make(map[string]any, len(h))
+	for k, v := range h {
+		if len(v) == 1 {
+			m[k] = v[0]
+			continue
+		}
+		m[k] = v
+	}
+	return json.Marshal(m)
+}
+
+func (h *Headers) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if m == nil {
+		*h = nil
+		return nil
+	}
+	*h = make(Headers, len(m))
+	for k, raw := range m {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			(*h)[k] = []string{s}
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return errors.New("header values must be a string or a list of strings")
+		}
+		(*h)[k] = values
+	}
+	return nil
+}
+
+// Get returns the first value of the header, comparing names case
+// insensitively.
+func (h Headers) Get(key string) string {
+	for k, v := range h {
+		if strings.EqualFold(k, key) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// SameSite is the SameSite attribute of a cookie.
+type SameSite string
+
+const (
+	SameSiteLax    SameSite = "Lax"
+	SameSiteStrict SameSite = "Strict"
+	SameSiteNone   SameSite = "None"
+)
+
+// Cookie is a cookie set by a response, written as a Set-Cookie header.
+type Cookie struct {
+	Name    string     `json:"name" validate:"required"`
+	Value   string     `json:"value"`
+	Path    string     `json:"path,omitempty"`
+	Domain  string     `json:"domain,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+	// MaxAge is the lifetime of the cookie in seconds. Negative values delete
+	// the cookie.
+	MaxAge   int      `json:"maxAge,omitempty"`
+	Secure   bool     `json:"secure,omitempty"`
+	HttpOnly bool     `json:"httpOnly,omitempty"`
+	SameSite SameSite `json:"sameSite,omitempty" validate:"omitempty,oneof=Lax Strict None"`
+}
+
+// HTTP returns the cookie as a net/http cookie.
+func (c *Cookie) HTTP() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		MaxAge:   c.MaxAge,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+	if c.Expires != nil {
+		cookie.Expires = *c.Expires
+	}
+	switch c.SameSite {
+	case SameSiteLax:
+		cookie.SameSite = http.SameSiteLaxMode
+	case SameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+	case SameSiteNone:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
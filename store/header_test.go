@@ -0,0 +1,33 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaders(t *testing.T) {
+	h := Headers{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"Content-Type": "text/plain", "Vary": ["Accept", "Origin"]}`), &h))
+	assert.Equal(t, Headers{"Content-Type": {"text/plain"}, "Vary": {"Accept", "Origin"}}, h)
+	assert.Equal(t, "text/plain", h.Get("content-type"))
+	assert.Equal(t, "", h.Get("Accept"))
+
+	b, err := json.Marshal(h)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Content-Type": "text/plain", "Vary": ["Accept", "Origin"]}`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"X-Count": 1}`), &h))
+}
+
+func TestCookie(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := Cookie{Name: "session", Value: "abc", Path: "/", Domain: "example.com", Expires: &expires, HttpOnly: true, SameSite: SameSiteStrict}
+	assert.Equal(t, &http.Cookie{
+		Name: "session", Value: "abc", Path: "/", Domain: "example.com", Expires: expires, HttpOnly: true, SameSite: http.SameSiteStrictMode,
+	}, c.HTTP())
+	assert.Equal(t, "session=abc; Path=/; Domain=example.com; Expires=Tue, 01 Jan 2030 00:00:00 GMT; HttpOnly; SameSite=Strict", c.HTTP().String())
+}
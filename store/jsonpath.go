@@ -0,0 +1,110 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is the subset of JSONPath supported by body matchers: a `$` root
+// followed by `.key`, `['key']`, `[index]`, `.*` or `[*]` selectors, e.g.
+// `$.items[0].id` or `$.items[*].tags`.
+type jsonPath []selector
+
+// selector picks values from a JSON value. An empty key with index -1 is a
+// wildcard.
+type selector struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", expr)
+	}
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			switch key {
+			case "":
+				return nil, fmt.Errorf("JSONPath %q has an empty key", expr)
+			case "*":
+				path = append(path, selector{index: -1})
+			default:
+				path = append(path, selector{key: key, isKey: true})
+			}
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed bracket", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				path = append(path, selector{index: -1})
+			case len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'':
+				path = append(path, selector{key: inner[1 : len(inner)-1], isKey: true})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil || i < 0 {
+					return nil, fmt.Errorf("JSONPath %q has an invalid index %q", expr, inner)
+				}
+				path = append(path, selector{index: i})
+			}
+		default:
+			return nil, fmt.Errorf("JSONPath %q is invalid at %q", expr, rest)
+		}
+	}
+	return path, nil
+}
+
+// eval returns every value selected by the path.
+func (p jsonPath) eval(doc any) []any {
+	values := []any{doc}
+	for _, s := range p {
+		next := []any{}
+		for _, v := range values {
+			next = append(next, s.eval(v)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func (s selector) eval(v any) []any {
+	switch v := v.(type) {
+	case map[string]any:
+		if s.isKey {
+			if child, ok := v[s.key]; ok {
+				return []any{child}
+			}
+			return nil
+		}
+		if s.index == -1 {
+			children := make([]any, 0, len(v))
+			for _, child := range v {
+				children = append(children, child)
+			}
+			return children
+		}
+	case []any:
+		if s.index == -1 {
+			return v
+		}
+		if !s.isKey && s.index < len(v) {
+			return []any{v[s.index]}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import "time"
+
+// SetExpiry turns the TTL of the endpoint into its ExpiresAt, counting from
+// now, and keeps ExpiresAt in UTC so that every backend returns the same time.
+func (a *Attributes) SetExpiry(now time.Time) {
+	if a.TTL > 0 {
+		t := now.Add(time.Duration(a.TTL) * time.Second)
+		a.ExpiresAt, a.TTL = &t, 0
+	}
+	if a.ExpiresAt != nil {
+		t := a.ExpiresAt.UTC()
+		a.ExpiresAt = &t
+	}
+}
+
+// Expired reports whether the endpoint has expired by now.
+func (a *Attributes) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// expiresAtColumn is the value of the expires_at column: the expiry time in
+// Unix nanoseconds, or 0 for endpoints that don't expire.
+func expiresAtColumn(a *Attributes) int64 {
+	if a.ExpiresAt == nil {
+		return 0
+	}
+	return a.ExpiresAt.UnixNano()
+}
+
+func scanExpiresAt(ns int64) *time.Time {
+	if ns == 0 {
+		return nil
+	}
+	t := time.Unix(0, ns).UTC()
+	return &t
+}
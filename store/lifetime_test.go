@@ -0,0 +1,29 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetExpiry(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	a := &Attributes{TTL: 90}
+	a.SetExpiry(now)
+	assert.Zero(t, a.TTL)
+	assert.Equal(t, time.Date(2030, 1, 1, 11, 1, 30, 0, time.UTC), *a.ExpiresAt)
+	assert.False(t, a.Expired(now.Add(89*time.Second)))
+	assert.True(t, a.Expired(now.Add(90*time.Second)))
+
+	a = &Attributes{ExpiresAt: &now}
+	a.SetExpiry(time.Now())
+	assert.Equal(t, time.UTC, a.ExpiresAt.Location())
+	assert.True(t, a.ExpiresAt.Equal(now))
+
+	a = &Attributes{}
+	a.SetExpiry(now)
+	assert.Nil(t, a.ExpiresAt)
+	assert.False(t, a.Expired(now))
+}
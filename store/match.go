@@ -0,0 +1,299 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Request holds the parts of an incoming request used to find the endpoint
+// that serves it.
+type Request struct {
+	// Namespace is the namespace of the client. Only endpoints in it match.
+	Namespace string
+	Method    string
+	Path      string
+	Query     url.Values
+	Headers   http.Header
+	Body      []byte
+	// Scenarios holds the current state of the scenarios by name. Missing
+	// scenarios are in StartedState.
+	Scenarios map[string]string
+	// Time is when the request was received. Endpoints that expired by then
+	// don't match it. The zero time matches every endpoint.
+	Time time.Time
+	// ClientCert is the subject of the verified certificate the client
+	// presented over TLS, like `CN=ci,O=Acme`, if any.
+	ClientCert string
+	// Protocol is the protocol version of the request, like HTTP/1.1 or
+	// HTTP/2.0.
+	Protocol string
+}
+
+// Matchers are optional conditions, on top of the verb and path, that a
+// request must meet to be served by an endpoint.
+type Matchers struct {
+	Query   map[string]ValueMatcher `json:"query,omitempty" validate:"omitempty,dive"`
+	Headers map[string]ValueMatcher `json:"headers,omitempty" validate:"omitempty,dive"`
+	Body    *BodyMatcher            `json:"body,omitempty"`
+	// ClientCert matches the subject of the client certificate, which is
+	// missing when the client didn't present one.
+	ClientCert *ValueMatcher `json:"clientCert,omitempty"`
+	// Protocol matches the protocol version of the request, like HTTP/2.0.
+	Protocol *ValueMatcher `json:"protocol,omitempty"`
+}
+
+// ValueMatcher matches the values of a query param or a header. A value
+// matcher can be given as a plain string, which is the same as Equals.
+//
+// The param or header must be present unless Present is false, in which case
+// it must be absent. When set, Equals and Matches must be met by at least one
+// of the values.
+type ValueMatcher struct {
+	Equals  string `json:"equals,omitempty"`
+	Matches string `json:"matches,omitempty" validate:"omitempty,regexp"`
+	Present *bool  `json:"present,omitempty"`
+}
+
+// BodyMatcher matches the request body. All the given conditions must be met.
+type BodyMatcher struct {
+	// JSON must be a subset of the request body decoded as JSON.
+	JSON any `json:"json,omitempty"`
+	// JSONEquals must be equal to the request body decoded as JSON.
+	JSONEquals any `json:"jsonEquals,omitempty"`
+	// JSONPath must select at least one value from the JSON request body.
+	JSONPath string `json:"jsonPath,omitempty" validate:"omitempty,jsonpath"`
+	// Matches is a regular expression the raw request body must match.
+	Matches string `json:"matches,omitempty" validate:"omitempty,regexp"`
+}
+
+func (v *ValueMatcher) UnmarshalJSON(b []byte) error {
+	var equals string
+	if err := json.Unmarshal(b, &equals); err == nil {
+		*v = ValueMatcher{Equals: equals}
+		return nil
+	}
+	type plain ValueMatcher
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+// find returns the endpoint serving the request. Endpoints must be sorted by
+// ID. When several of them match, the winner is the one with the most
+// specific path, then the one with the most matchers, counting a required
+// scenario state as one, and then the one with the highest priority. The
+// oldest endpoint wins any remaining tie.
+func find(endpoints []*Endpoint, r *Request) *Match {
+	var (
+		best     *Match
+		bestPath []segment
+	)
+	for _, e := range endpoints {
+		template := parsePath(e.Attributes.Path)
+		params, ok := matchPath(template, r.Path)
+		if !ok || !e.Attributes.Match.Matches(r) || !e.Attributes.Scenario.allows(r.Scenarios) ||
+			(!r.Time.IsZero() && e.Attributes.Expired(r.Time)) {
+			continue
+		}
+		if best == nil || precedes(e, template, best.Endpoint, bestPath) {
+			best = &Match{Endpoint: e, Params: params}
+			bestPath = template
+		}
+	}
+	return best
+}
+
+func precedes(a *Endpoint, aPath []segment, b *Endpoint, bPath []segment) bool {
+	switch {
+	case moreSpecific(aPath, bPath):
+		return true
+	case moreSpecific(bPath, aPath):
+		return false
+	}
+	ac := a.Attributes.Match.count() + a.Attributes.Scenario.count()
+	bc := b.Attributes.Match.count() + b.Attributes.Scenario.count()
+	if ac != bc {
+		return ac > bc
+	}
+	return a.Attributes.Priority > b.Attributes.Priority
+}
+
+// count returns the number of conditions, used to rank endpoints matching
+// the same request.
+func (m *Matchers) count() int {
+	if m == nil {
+		return 0
+	}
+	n := len(m.Query) + len(m.Headers)
+	for _, v := range []*ValueMatcher{m.ClientCert, m.Protocol} {
+		if v != nil {
+			n++
+		}
+	}
+	if m.Body != nil {
+		for _, set := range []bool{m.Body.JSON != nil, m.Body.JSONEquals != nil, m.Body.JSONPath != "", m.Body.Matches != ""} {
+			if set {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Matches reports whether the request meets every condition. Nil matchers
+// match any request.
+func (m *Matchers) Matches(r *Request) bool {
+	if m == nil {
+		return true
+	}
+	for k, v := range m.Query {
+		if !v.matches(r.Query[k]) {
+			return false
+		}
+	}
+	for k, v := range m.Headers {
+		if !v.matches(r.Headers.Values(k)) {
+			return false
+		}
+	}
+	if m.ClientCert != nil && !m.ClientCert.matches(single(r.ClientCert)) {
+		return false
+	}
+	if m.Protocol != nil && !m.Protocol.matches(single(r.Protocol)) {
+		return false
+	}
+	return m.Body.matches(r.Body)
+}
+
+func (v ValueMatcher) matches(values []string) bool {
+	if v.Present != nil && !*v.Present {
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return false
+	}
+	if v.Equals != "" && !anyValue(values, func(s string) bool { return s == v.Equals }) {
+		return false
+	}
+	if v.Matches != "" && !anyValue(values, pattern(v.Matches).MatchString) {
+		return false
+	}
+	return true
+}
+
+// single returns the value as a list of values, empty when it's not set.
+func single(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+func anyValue(values []string, fn func(string) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BodyMatcher) matches(body []byte) bool {
+	if b == nil {
+		return true
+	}
+	if b.Matches != "" && !pattern(b.Matches).Match(body) {
+		return false
+	}
+	if b.JSON == nil && b.JSONEquals == nil && b.JSONPath == "" {
+		return true
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return false
+	}
+	if b.JSON != nil && !contains(doc, b.JSON) {
+		return false
+	}
+	if b.JSONEquals != nil && !reflect.DeepEqual(doc, b.JSONEquals) {
+		return false
+	}
+	if b.JSONPath != "" {
+		path, err := parseJSONPath(b.JSONPath)
+		if err != nil || len(path.eval(doc)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether want is a subset of got: objects must contain
+// every key of want, arrays must contain every element of want and any other
+// value must be equal.
+func contains(got, want any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if gv, ok := g[k]; !ok || !contains(gv, v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return false
+		}
+		for _, v := range w {
+			if !anyElement(g, v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(got, want)
+	}
+}
+
+func anyElement(got []any, want any) bool {
+	for _, g := range got {
+		if contains(g, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns caches the compiled regular expressions of the matchers, since
+// they're checked against every request.
+var patterns sync.Map
+
+// pattern returns the compiled regular expression. Matchers are validated
+// before they're stored, so their expressions always compile.
+func pattern(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	patterns.Store(expr, re)
+	return re
+}
+
+func validateRegexp(fl validator.FieldLevel) bool {
+	_, err := regexp.Compile(fl.Field().String())
+	return err == nil
+}
+
+func validateJSONPath(fl validator.FieldLevel) bool {
+	_, err := parseJSONPath(fl.Field().String())
+	return err == nil
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	endpoints := []*Endpoint{}
+	for i, path := range []string{
+		"/static/*",
+		"/users/{id}",
+		"/users/{id}/orders/{orderId}",
+		"/users/me",
+		"/users/{name}",
+		"/*",
+		"/users/{id}/orders/latest",
+	} {
+		endpoints = append(endpoints, &Endpoint{ID: i + 1, Attributes: Attributes{Path: path}})
+	}
+
+	tests := []struct {
+		path       string
+		wantID     int
+		wantParams map[string]string
+	}{
+		{path: "/users/me", wantID: 4, wantParams: map[string]string{}},
+		{path: "/users/42", wantID: 2, wantParams: map[string]string{"id": "42"}},
+		{path: "/users/42/orders/7", wantID: 3, wantParams: map[string]string{"id": "42", "orderId": "7"}},
+		{path: "/users/42/orders/latest", wantID: 7, wantParams: map[string]string{"id": "42"}},
+		{path: "/static/css/main.css", wantID: 1, wantParams: map[string]string{"*": "css/main.css"}},
+		{path: "/static/", wantID: 1, wantParams: map[string]string{"*": ""}},
+		{path: "/static", wantID: 6, wantParams: map[string]string{"*": "static"}},
+		{path: "/users/42/", wantID: 6, wantParams: map[string]string{"*": "users/42/"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			m := find(endpoints, &Request{Path: test.path})
+			assert.NotNil(t, m)
+			assert.Equal(t, test.wantID, m.Endpoint.ID)
+			assert.Equal(t, test.wantParams, m.Params)
+		})
+	}
+
+	t.Run("returns nil when nothing matches", func(t *testing.T) {
+		assert.Nil(t, find(endpoints[:5], &Request{Path: "/nope"}))
+	})
+}
+
+func TestFindWithMatchers(t *testing.T) {
+	endpoints := []*Endpoint{
+		{ID: 1, Attributes: Attributes{Path: "/search"}},
+		{ID: 2, Attributes: Attributes{Path: "/search", Match: &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "foo"}}}}},
+		{ID: 3, Attributes: Attributes{Path: "/search", Match: &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "bar"}}}}},
+		{ID: 4, Attributes: Attributes{Path: "/search", Match: &Matchers{
+			Query:   map[string]ValueMatcher{"q": {Equals: "foo"}},
+			Headers: map[string]ValueMatcher{"Authorization": {Matches: "^Bearer "}},
+		}}},
+		{ID: 5, Attributes: Attributes{Path: "/search", Priority: 1, Match: &Matchers{Query: map[string]ValueMatcher{"q": {Matches: "^ba"}}}}},
+		{ID: 6, Attributes: Attributes{Path: "/{any}", Match: &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "foo"}, "page": {}}}}},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		headers http.Header
+		wantID  int
+	}{
+		{name: "no matchers", query: "", wantID: 1},
+		{name: "query param", query: "q=foo", wantID: 2},
+		{name: "priority breaks ties", query: "q=bar", wantID: 5},
+		{name: "most matchers win", query: "q=foo", headers: http.Header{"Authorization": {"Bearer x"}}, wantID: 4},
+		{name: "path specificity beats matchers", query: "q=foo&page=1", wantID: 2},
+		{name: "unmatched conditions fall back", query: "q=baz", wantID: 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			assert.NoError(t, err)
+			m := find(endpoints, &Request{Path: "/search", Query: query, Headers: test.headers})
+			assert.NotNil(t, m)
+			assert.Equal(t, test.wantID, m.Endpoint.ID)
+		})
+	}
+}
+
+func TestFindWithScenarios(t *testing.T) {
+	endpoints := []*Endpoint{
+		{ID: 1, Attributes: Attributes{Path: "/cart"}},
+		{ID: 2, Attributes: Attributes{Path: "/cart", Scenario: &Scenario{Name: "checkout", RequiredState: StartedState}}},
+		{ID: 3, Attributes: Attributes{Path: "/cart", Scenario: &Scenario{Name: "checkout", RequiredState: "paid"}}},
+	}
+
+	tests := []struct {
+		name   string
+		states map[string]string
+		wantID int
+	}{
+		{name: "scenarios start in the started state", wantID: 2},
+		{name: "required state", states: map[string]string{"checkout": "paid"}, wantID: 3},
+		{name: "unknown state falls back", states: map[string]string{"checkout": "shipped"}, wantID: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := find(endpoints, &Request{Path: "/cart", Scenarios: test.states})
+			assert.NotNil(t, m)
+			assert.Equal(t, test.wantID, m.Endpoint.ID)
+		})
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	present, absent := true, false
+	body := []byte(`{"amount":10,"items":[{"id":1,"tags":["a","b"]},{"id":2}],"user":{"name":"Susan"}}`)
+	tests := []struct {
+		name     string
+		matchers *Matchers
+		want     bool
+	}{
+		{name: "nil matchers", want: true},
+		{name: "header equals", matchers: &Matchers{Headers: map[string]ValueMatcher{"x-api-key": {Equals: "secret"}}}, want: true},
+		{name: "header equals fails", matchers: &Matchers{Headers: map[string]ValueMatcher{"X-Api-Key": {Equals: "nope"}}}},
+		{name: "header regex", matchers: &Matchers{Headers: map[string]ValueMatcher{"Accept": {Matches: "json$"}}}, want: true},
+		{name: "header present", matchers: &Matchers{Headers: map[string]ValueMatcher{"Accept": {Present: &present}}}, want: true},
+		{name: "header absent", matchers: &Matchers{Headers: map[string]ValueMatcher{"Cookie": {Present: &absent}}}, want: true},
+		{name: "header absent fails", matchers: &Matchers{Headers: map[string]ValueMatcher{"Accept": {Present: &absent}}}},
+		{name: "missing header", matchers: &Matchers{Headers: map[string]ValueMatcher{"Cookie": {}}}},
+		{name: "query with several values", matchers: &Matchers{Query: map[string]ValueMatcher{"tag": {Equals: "b"}}}, want: true},
+		{name: "JSON subset", matchers: &Matchers{Body: &BodyMatcher{JSON: map[string]any{"amount": 10.0, "items": []any{map[string]any{"id": 2.0}}}}}, want: true},
+		{name: "JSON subset fails", matchers: &Matchers{Body: &BodyMatcher{JSON: map[string]any{"amount": 11.0}}}},
+		{name: "JSON equals", matchers: &Matchers{Body: &BodyMatcher{JSONEquals: map[string]any{"user": map[string]any{"name": "Susan"}, "amount": 10.0, "items": []any{map[string]any{"id": 1.0, "tags": []any{"a", "b"}}, map[string]any{"id": 2.0}}}}}, want: true},
+		{name: "JSON equals fails on a subset", matchers: &Matchers{Body: &BodyMatcher{JSONEquals: map[string]any{"amount": 10.0, "items": []any{map[string]any{"id": 2.0}}, "user": map[string]any{"name": "Susan"}}}}},
+		{name: "JSONPath", matchers: &Matchers{Body: &BodyMatcher{JSONPath: "$.items[*].tags[1]"}}, want: true},
+		{name: "JSONPath fails", matchers: &Matchers{Body: &BodyMatcher{JSONPath: "$.items[1].tags"}}},
+		{name: "body regex", matchers: &Matchers{Body: &BodyMatcher{Matches: `"name":"Su`}}, want: true},
+		{name: "body regex fails", matchers: &Matchers{Body: &BodyMatcher{Matches: `^\[`}}},
+		{name: "client certificate", matchers: &Matchers{ClientCert: &ValueMatcher{Matches: "^CN=ci,"}}, want: true},
+		{name: "client certificate fails", matchers: &Matchers{ClientCert: &ValueMatcher{Equals: "CN=prod"}}},
+		{name: "protocol", matchers: &Matchers{Protocol: &ValueMatcher{Equals: "HTTP/2.0"}}, want: true},
+		{name: "protocol fails", matchers: &Matchers{Protocol: &ValueMatcher{Matches: `^HTTP/1\.`}}},
+	}
+
+	r := &Request{
+		Query:      url.Values{"tag": {"a", "b"}},
+		Headers:    http.Header{"X-Api-Key": {"secret"}, "Accept": {"application/json"}},
+		Body:       body,
+		ClientCert: "CN=ci,O=Acme",
+		Protocol:   "HTTP/2.0",
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.matchers.Matches(r))
+		})
+	}
+
+	t.Run("JSON matchers don't match bodies that are not JSON", func(t *testing.T) {
+		m := &Matchers{Body: &BodyMatcher{JSONPath: "$"}}
+		assert.False(t, m.Matches(&Request{Body: []byte("hello")}))
+	})
+
+	t.Run("client certificate matchers need a certificate", func(t *testing.T) {
+		m := &Matchers{ClientCert: &ValueMatcher{Matches: ".*"}}
+		assert.False(t, m.Matches(&Request{}))
+	})
+}
+
+func TestValueMatcherUnmarshal(t *testing.T) {
+	m := &Matchers{}
+	err := json.Unmarshal([]byte(`{"query":{"q":"foo","page":{"matches":"^\\d+$"}}}`), m)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ValueMatcher{"q": {Equals: "foo"}, "page": {Matches: `^\d+$`}}, m.Query)
+}
+
+func TestParseJSONPath(t *testing.T) {
+	for _, valid := range []string{"$", "$.a", "$.a.b[0]", "$['a b'][*]", "$.*.c"} {
+		_, err := parseJSONPath(valid)
+		assert.NoError(t, err, valid)
+	}
+	for _, invalid := range []string{"", "a.b", "$.", "$[", "$[x]", "$a"} {
+		_, err := parseJSONPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	re := pattern(`^ci-\d+$`)
+	assert.True(t, re.MatchString("ci-1"))
+	assert.Same(t, re, pattern(`^ci-\d+$`), "patterns are compiled once")
+}
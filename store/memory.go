@@ -0,0 +1,237 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory is a pure Go Storage that keeps the endpoints in memory. It doesn't
+// need CGO and it's useful for tests or when persistence is not required.
+type Memory struct {
+	mu        sync.RWMutex
+	lastID    int
+	endpoints []*Endpoint
+	// namespaces holds the namespaces created with CreateNamespace.
+	namespaces map[string]bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{namespaces: map[string]bool{}}
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Seed creates the built-in endpoints, skipping those that already exist.
+func (m *Memory) Seed() error {
+	_, _, err := CreateEndpoints(m, seed())
+	return err
+}
+
+func (m *Memory) FetchEndpoints() (*Many, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data := make([]*Endpoint, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		c, err := clone(e)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, c)
+	}
+
+	return &Many{Data: data}, nil
+}
+
+func (m *Memory) CreateEndpoint(endpoint *Endpoint) (*One, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := clone(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	m.lastID++
+	e.ID = m.lastID
+	m.endpoints = append(m.endpoints, e)
+
+	return cloneOne(e)
+}
+
+func (m *Memory) UpdateEndpoint(id string, endpoint *Endpoint) (*One, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(id)
+	if i < 0 {
+		return nil, nil
+	}
+	e, err := clone(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	e.ID = m.endpoints[i].ID
+	m.endpoints[i] = e
+
+	return cloneOne(e)
+}
+
+func (m *Memory) DeleteEndpoint(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(id)
+	if i < 0 {
+		return false, nil
+	}
+	m.endpoints = append(m.endpoints[:i], m.endpoints[i+1:]...)
+
+	return true, nil
+}
+
+func (m *Memory) DeleteExpired(now time.Time) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []int{}
+	kept := m.endpoints[:0]
+	for _, e := range m.endpoints {
+		if e.Attributes.Expired(now) {
+			ids = append(ids, e.ID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.endpoints = kept
+
+	return ids, nil
+}
+
+func (m *Memory) CreateNamespace(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.namespaces[name] || m.counts()[name] > 0 {
+		return false, nil
+	}
+	m.namespaces[name] = true
+
+	return true, nil
+}
+
+func (m *Memory) FetchNamespaces() ([]*Namespace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := m.counts()
+	for name := range m.namespaces {
+		if _, ok := counts[name]; !ok {
+			counts[name] = 0
+		}
+	}
+	namespaces := make([]*Namespace, 0, len(counts))
+	for name, n := range counts {
+		namespaces = append(namespaces, &Namespace{Name: name, Endpoints: n})
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	return namespaces, nil
+}
+
+func (m *Memory) DeleteNamespace(name string) ([]int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []int{}
+	kept := m.endpoints[:0]
+	for _, e := range m.endpoints {
+		if e.Attributes.Namespace == name {
+			ids = append(ids, e.ID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.endpoints = kept
+	existed := m.namespaces[name] || len(ids) > 0
+	delete(m.namespaces, name)
+
+	return ids, existed, nil
+}
+
+// counts returns the number of endpoints of each namespace, but for the
+// default one. m.mu must be held by the caller.
+func (m *Memory) counts() map[string]int {
+	counts := map[string]int{}
+	for _, e := range m.endpoints {
+		if ns := e.Attributes.Namespace; ns != "" {
+			counts[ns]++
+		}
+	}
+	return counts
+}
+
+func (m *Memory) FindEndpoint(r *Request) (*Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := []*Endpoint{}
+	for _, e := range m.endpoints {
+		if e.Attributes.Namespace == r.Namespace && e.Attributes.Verb == r.Method {
+			candidates = append(candidates, e)
+		}
+	}
+	match := find(candidates, r)
+	if match == nil {
+		return nil, nil
+	}
+	e, err := clone(match.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	match.Endpoint = e
+
+	return match, nil
+}
+
+// index returns the position of the endpoint with the given id or -1 when
+// there's none. m.mu must be held by the caller.
+func (m *Memory) index(id string) int {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return -1
+	}
+	for i, e := range m.endpoints {
+		if e.ID == n {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneOne(e *Endpoint) (*One, error) {
+	c, err := clone(e)
+	if err != nil {
+		return nil, err
+	}
+	return &One{Data: c}, nil
+}
+
+// clone returns a deep copy of e so callers can't modify the stored data.
+// Endpoints are copied through their JSON representation, like the SQLite
+// store does, so both backends return identical endpoints.
+func clone(e *Endpoint) (*Endpoint, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	c := &Endpoint{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
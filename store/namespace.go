@@ -0,0 +1,28 @@
+package store
+
+import (
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// namespaceName is the format of namespace names, which are used in paths.
+var namespaceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Namespace isolates a set of endpoints from the others, so that several
+// clients can share a server. Endpoints without a namespace are in the
+// default one, which is not listed.
+type Namespace struct {
+	Name string
+	// Endpoints is the number of endpoints in the namespace.
+	Endpoints int
+}
+
+// ValidNamespace reports whether name can be used as a namespace.
+func ValidNamespace(name string) bool {
+	return namespaceName.MatchString(name)
+}
+
+func validateNamespace(fl validator.FieldLevel) bool {
+	return ValidNamespace(fl.Field().String())
+}
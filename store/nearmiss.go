@@ -0,0 +1,154 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NearMiss is an endpoint that almost matched a request, along with the
+// reasons why it didn't.
+type NearMiss struct {
+	Endpoint *Endpoint
+	Reasons  []string
+}
+
+// maxNearMissReasons is the number of differences above which an endpoint is
+// too far from a request to be considered a near miss.
+const maxNearMissReasons = 3
+
+// NearMisses returns up to limit endpoints that are close to matching the
+// request, closest first. Endpoints are close when their path matches the
+// request's or differs from it by a trailing slash or by one of its segments.
+func NearMisses(endpoints []*Endpoint, r *Request, limit int) []*NearMiss {
+	misses := []*NearMiss{}
+	for _, e := range endpoints {
+		if e.Attributes.Namespace != r.Namespace || (!r.Time.IsZero() && e.Attributes.Expired(r.Time)) {
+			continue
+		}
+		reasons, ok := explainPath(parsePath(e.Attributes.Path), r.Path)
+		if !ok {
+			continue
+		}
+		if e.Attributes.Verb != r.Method {
+			reasons = append(reasons, fmt.Sprintf("verb is %s, expected %s", r.Method, e.Attributes.Verb))
+		}
+		reasons = append(reasons, e.Attributes.Match.explain(r)...)
+		reasons = append(reasons, e.Attributes.Scenario.explain(r.Scenarios)...)
+		if len(reasons) == 0 || len(reasons) > maxNearMissReasons {
+			continue
+		}
+		misses = append(misses, &NearMiss{Endpoint: e, Reasons: reasons})
+	}
+	sort.SliceStable(misses, func(i, j int) bool {
+		return len(misses[i].Reasons) < len(misses[j].Reasons)
+	})
+	if len(misses) > limit {
+		misses = misses[:limit]
+	}
+	return misses
+}
+
+// explainPath returns why the path doesn't match the template. It's not ok
+// when they differ by more than a trailing slash or a segment.
+func explainPath(template []segment, path string) ([]string, bool) {
+	if _, ok := matchPath(template, path); ok {
+		return nil, true
+	}
+	trimmed := strings.TrimSuffix(path, "/")
+	if trimmed == path {
+		trimmed = path + "/"
+	}
+	if _, ok := matchPath(template, trimmed); ok {
+		return []string{fmt.Sprintf("path %s differs by a trailing slash", path)}, true
+	}
+
+	// A single segment path differing in its only segment is a different path.
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) != len(template) || len(parts) < 2 {
+		return nil, false
+	}
+	var reasons []string
+	for i, s := range template {
+		if s.kind == literal && s.value != parts[i] {
+			reasons = append(reasons, fmt.Sprintf("path segment %d is `%s`, expected `%s`", i+1, parts[i], s.value))
+		}
+	}
+	return reasons, len(reasons) == 1
+}
+
+// explain returns a reason for every condition the request doesn't meet.
+func (m *Matchers) explain(r *Request) []string {
+	if m == nil {
+		return nil
+	}
+	var reasons []string
+	for _, k := range sortedKeys(m.Query) {
+		if reason := m.Query[k].explain(r.Query[k]); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("query param `%s` %s", k, reason))
+		}
+	}
+	for _, k := range sortedKeys(m.Headers) {
+		if reason := m.Headers[k].explain(r.Headers.Values(k)); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("header `%s` %s", k, reason))
+		}
+	}
+	if m.ClientCert != nil {
+		if reason := m.ClientCert.explain(single(r.ClientCert)); reason != "" {
+			reasons = append(reasons, "client certificate subject "+reason)
+		}
+	}
+	if m.Protocol != nil {
+		if reason := m.Protocol.explain(single(r.Protocol)); reason != "" {
+			reasons = append(reasons, "protocol "+reason)
+		}
+	}
+	return append(reasons, m.Body.explain(r.Body)...)
+}
+
+func (v ValueMatcher) explain(values []string) string {
+	if v.matches(values) {
+		return ""
+	}
+	switch {
+	case v.Present != nil && !*v.Present:
+		return "is present, expected it to be absent"
+	case len(values) == 0:
+		return "is missing"
+	case v.Equals != "" && v.Matches != "":
+		return fmt.Sprintf("is `%s`, expected `%s` matching `%s`", strings.Join(values, ","), v.Equals, v.Matches)
+	case v.Equals != "":
+		return fmt.Sprintf("is `%s`, expected `%s`", strings.Join(values, ","), v.Equals)
+	default:
+		return fmt.Sprintf("is `%s`, expected to match `%s`", strings.Join(values, ","), v.Matches)
+	}
+}
+
+func (b *BodyMatcher) explain(body []byte) []string {
+	if b == nil {
+		return nil
+	}
+	var reasons []string
+	if b.Matches != "" && !(&BodyMatcher{Matches: b.Matches}).matches(body) {
+		reasons = append(reasons, fmt.Sprintf("body doesn't match `%s`", b.Matches))
+	}
+	if b.JSON != nil && !(&BodyMatcher{JSON: b.JSON}).matches(body) {
+		reasons = append(reasons, "body doesn't contain the expected JSON")
+	}
+	if b.JSONEquals != nil && !(&BodyMatcher{JSONEquals: b.JSONEquals}).matches(body) {
+		reasons = append(reasons, "body isn't the expected JSON")
+	}
+	if b.JSONPath != "" && !(&BodyMatcher{JSONPath: b.JSONPath}).matches(body) {
+		reasons = append(reasons, fmt.Sprintf("body has nothing at `%s`", b.JSONPath))
+	}
+	return reasons
+}
+
+func sortedKeys(m map[string]ValueMatcher) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearMisses(t *testing.T) {
+	endpoints := []*Endpoint{
+		{ID: 1, Attributes: Attributes{Verb: http.MethodGet, Path: "/users/{id}/orders"}},
+		{ID: 2, Attributes: Attributes{Verb: http.MethodPost, Path: "/users/{id}/orders/"}},
+		{ID: 3, Attributes: Attributes{Verb: http.MethodPost, Path: "/users/{id}/invoices", Match: &Matchers{
+			Query:   map[string]ValueMatcher{"page": {Equals: "1"}},
+			Headers: map[string]ValueMatcher{"Authorization": {}},
+		}}},
+		{ID: 4, Attributes: Attributes{Verb: http.MethodPost, Path: "/users/{id}/orders", Match: &Matchers{
+			Body: &BodyMatcher{JSON: map[string]any{"amount": 10.0}, JSONPath: "$.items"},
+		}}},
+		{ID: 5, Attributes: Attributes{Verb: http.MethodPost, Path: "/payments"}},
+	}
+	r := &Request{
+		Method: http.MethodPost,
+		Path:   "/users/42/orders",
+		Query:  url.Values{"page": {"2"}},
+		Body:   []byte(`{"amount":20}`),
+	}
+
+	got := NearMisses(endpoints, r, 5)
+	want := []*NearMiss{
+		{Endpoint: endpoints[0], Reasons: []string{"verb is POST, expected GET"}},
+		{Endpoint: endpoints[1], Reasons: []string{"path /users/42/orders differs by a trailing slash"}},
+		{Endpoint: endpoints[3], Reasons: []string{"body doesn't contain the expected JSON", "body has nothing at `$.items`"}},
+		{Endpoint: endpoints[2], Reasons: []string{
+			"path segment 3 is `orders`, expected `invoices`",
+			"query param `page` is `2`, expected `1`",
+			"header `Authorization` is missing",
+		}},
+	}
+	assert.Equal(t, want, got)
+
+	t.Run("limit", func(t *testing.T) {
+		assert.Len(t, NearMisses(endpoints, r, 2), 2)
+	})
+
+	t.Run("scenario state", func(t *testing.T) {
+		e := &Endpoint{ID: 6, Attributes: Attributes{Verb: http.MethodGet, Path: "/cart", Scenario: &Scenario{Name: "checkout", RequiredState: "paid"}}}
+		got := NearMisses([]*Endpoint{e}, &Request{Method: http.MethodGet, Path: "/cart"}, 5)
+		assert.Equal(t, []*NearMiss{{Endpoint: e, Reasons: []string{"scenario `checkout` is in state `Started`, expected `paid`"}}}, got)
+	})
+
+	t.Run("single segment paths don't miss by one segment", func(t *testing.T) {
+		assert.Empty(t, NearMisses(endpoints, &Request{Method: http.MethodPost, Path: "/refunds"}, 5))
+	})
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Paths can be templates made of three kinds of segments, listed from the
+// most to the least specific:
+//
+//   - literal: `/users` only matches the `users` segment.
+//   - param: `/{id}` matches any single segment and captures it as `id`.
+//   - wildcard: `/*` must be the last segment and matches the rest of the
+//     path, which is captured as `*`.
+//
+// When more than one endpoint matches a request, segments are compared from
+// left to right and the first more specific one wins, so `/users/me` beats
+// `/users/{id}`, which beats `/users/*`. Ties go to the oldest endpoint.
+const (
+	literal = iota
+	param
+	wildcard
+)
+
+// WildcardParam is the name under which the path matched by a wildcard is
+// captured.
+const WildcardParam = "*"
+
+// Match is the endpoint that serves a request along with the values captured
+// by its path template.
+type Match struct {
+	Endpoint *Endpoint
+	Params   map[string]string
+}
+
+type segment struct {
+	kind  int
+	value string
+}
+
+func parsePath(path string) []segment {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	segments := make([]segment, len(parts))
+	for i, p := range parts {
+		switch {
+		case p == "*":
+			segments[i] = segment{kind: wildcard, value: WildcardParam}
+		case len(p) > 2 && strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+			segments[i] = segment{kind: param, value: p[1 : len(p)-1]}
+		default:
+			segments[i] = segment{kind: literal, value: p}
+		}
+	}
+	return segments
+}
+
+// MatchPath reports whether path is matched by the given path template and
+// returns the captured params.
+func MatchPath(template, path string) (map[string]string, bool) {
+	return matchPath(parsePath(template), path)
+}
+
+// matchPath reports whether path is matched by the template and returns the
+// captured params.
+func matchPath(template []segment, path string) (map[string]string, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	params := map[string]string{}
+	for i, s := range template {
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch s.kind {
+		case wildcard:
+			params[s.value] = strings.Join(parts[i:], "/")
+			return params, true
+		case literal:
+			if s.value != parts[i] {
+				return nil, false
+			}
+		case param:
+			params[s.value] = parts[i]
+		}
+	}
+	if len(parts) != len(template) {
+		return nil, false
+	}
+	return params, true
+}
+
+// moreSpecific reports whether template a takes precedence over template b.
+func moreSpecific(a, b []segment) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].kind != b[i].kind {
+			return a[i].kind < b[i].kind
+		}
+	}
+	return len(a) > len(b)
+}
+
+// Route identifies the requests served by the endpoint. Two endpoints with
+// the same route can't coexist since one would shadow the other.
+func (a Attributes) Route() string {
+	route := a.Verb + " " + a.normalizedPath()
+	if a.Match != nil {
+		// Map keys are sorted when marshaled, so equal matchers always produce
+		// the same JSON. The error is impossible as matchers come from JSON.
+		m, _ := json.Marshal(a.Match)
+		route += " " + string(m)
+	}
+	if a.Scenario != nil && a.Scenario.RequiredState != "" {
+		route += fmt.Sprintf(" [%s=%s]", a.Scenario.Name, a.Scenario.RequiredState)
+	}
+	return route
+}
+
+func (a Attributes) normalizedPath() string {
+	segments := parsePath(a.Path)
+	parts := make([]string, len(segments))
+	for i, s := range segments {
+		switch s.kind {
+		case literal:
+			parts[i] = s.value
+		case param:
+			parts[i] = "{}"
+		case wildcard:
+			parts[i] = "*"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// NewValidator returns a validator aware of the custom tags used by the
+// store's types.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	// Registering validations only fails on empty tags or nil functions.
+	_ = v.RegisterValidation("pathtemplate", validatePathTemplate)
+	_ = v.RegisterValidation("regexp", validateRegexp)
+	_ = v.RegisterValidation("jsonpath", validateJSONPath)
+	_ = v.RegisterValidation("fixture", validateFixture)
+	_ = v.RegisterValidation("namespace", validateNamespace)
+	v.RegisterStructValidation(validateResponse, Response{})
+	return v
+}
+
+// validatePathTemplate checks that wildcards are only used as the last
+// segment and that param names are unique.
+func validatePathTemplate(fl validator.FieldLevel) bool {
+	segments := parsePath(fl.Field().String())
+	names := map[string]bool{}
+	for i, s := range segments {
+		switch s.kind {
+		case wildcard:
+			if i != len(segments)-1 {
+				return false
+			}
+		case param:
+			if names[s.value] || strings.ContainsAny(s.value, "{}") {
+				return false
+			}
+			names[s.value] = true
+		}
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+	a := Attributes{Verb: "GET", Path: "/users/{id}/*"}
+	b := Attributes{Verb: "GET", Path: "/users/{name}/*"}
+	c := Attributes{Verb: "GET", Path: "/users/42/*"}
+
+	assert.Equal(t, "GET /users/{}/*", a.Route())
+	assert.Equal(t, a.Route(), b.Route())
+	assert.NotEqual(t, a.Route(), c.Route())
+
+	a.Match = &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "foo"}}}
+	assert.NotEqual(t, a.Route(), b.Route())
+	b.Match = &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "foo"}}}
+	assert.Equal(t, a.Route(), b.Route())
+}
@@ -0,0 +1,84 @@
+package store
+
+import "fmt"
+
+// StartedState is the state every scenario is in until an endpoint moves it
+// somewhere else.
+const StartedState = "Started"
+
+// SequenceMode sets which response of a sequence serves each request.
+type SequenceMode string
+
+const (
+	// StickOnLast serves the responses in order and then repeats the last
+	// one. It's the default mode.
+	StickOnLast SequenceMode = "stick-on-last"
+	// Cycle serves the responses in order and starts over after the last.
+	Cycle SequenceMode = "cycle"
+	// RoundRobin spreads the requests over the responses in proportion to
+	// their weights, interleaving them as evenly as possible.
+	RoundRobin SequenceMode = "round-robin"
+	// WeightedRandom picks a random response for each request, with a
+	// probability proportional to its weight.
+	WeightedRandom SequenceMode = "weighted-random"
+)
+
+// Sequence is an ordered list of responses served by a single endpoint, used
+// to model flows like polling or retries.
+type Sequence struct {
+	Mode      SequenceMode `json:"mode,omitempty" validate:"omitempty,oneof=stick-on-last cycle round-robin weighted-random"`
+	Responses []Response   `json:"responses" validate:"required,min=1,dive"`
+}
+
+// Scenario ties an endpoint to a named state machine. Endpoints requiring a
+// state only match while the scenario is in it, and endpoints with a new
+// state move the scenario to it after serving a request.
+type Scenario struct {
+	Name          string `json:"name" validate:"required"`
+	RequiredState string `json:"requiredState,omitempty"`
+	NewState      string `json:"newState,omitempty"`
+}
+
+// Responses returns the responses the endpoint can serve: those of its
+// sequence or its single response. They point into the attributes, so they
+// can be changed in place.
+func (a *Attributes) Responses() []*Response {
+	if a.Sequence == nil {
+		return []*Response{&a.Response}
+	}
+	responses := make([]*Response, len(a.Sequence.Responses))
+	for i := range a.Sequence.Responses {
+		responses[i] = &a.Sequence.Responses[i]
+	}
+	return responses
+}
+
+// state returns the current state of the named scenario.
+func state(states map[string]string, name string) string {
+	if s, ok := states[name]; ok {
+		return s
+	}
+	return StartedState
+}
+
+// allows reports whether the scenario is in the state the endpoint requires.
+// Endpoints without a scenario or a required state are always allowed.
+func (s *Scenario) allows(states map[string]string) bool {
+	return s == nil || s.RequiredState == "" || state(states, s.Name) == s.RequiredState
+}
+
+// count returns 1 when the endpoint requires a state, which ranks it above
+// otherwise equal endpoints.
+func (s *Scenario) count() int {
+	if s == nil || s.RequiredState == "" {
+		return 0
+	}
+	return 1
+}
+
+func (s *Scenario) explain(states map[string]string) []string {
+	if s.allows(states) {
+		return nil
+	}
+	return []string{fmt.Sprintf("scenario `%s` is in state `%s`, expected `%s`", s.Name, state(states, s.Name), s.RequiredState)}
+}
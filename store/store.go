@@ -4,48 +4,70 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // SQL driver
 )
 
-const dbSchema = `CREATE TABLE IF NOT EXISTS endpoints (
+// memoryDSN is used when New is called without a DSN. The shared cache keeps
+// the database alive across the connections of the pool.
+const memoryDSN = "file::memory:?cache=shared"
+
+// migrations holds every schema change applied to the database. The position
+// of a migration in the slice is its version, stored in SQLite's user_version
+// pragma, so existing entries MUST NOT be modified: append new ones instead.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS endpoints (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   type TEXT NOT NULL, verb TEXT NOT NULL,
   path TEXT NOT NULL, code INTEGER NOT NULL,
   headers TEXT NOT NULL, body TEXT NOT NULL
-)`
+)`,
+	`ALTER TABLE endpoints ADD COLUMN template INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE endpoints ADD COLUMN matchers TEXT NOT NULL DEFAULT 'null';
+	ALTER TABLE endpoints ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE endpoints ADD COLUMN sequence TEXT NOT NULL DEFAULT 'null';
+	ALTER TABLE endpoints ADD COLUMN scenario TEXT NOT NULL DEFAULT 'null'`,
+	`ALTER TABLE endpoints ADD COLUMN delay TEXT NOT NULL DEFAULT 'null';
+	ALTER TABLE endpoints ADD COLUMN fault TEXT NOT NULL DEFAULT 'null'`,
+	`ALTER TABLE endpoints ADD COLUMN encoding TEXT NOT NULL DEFAULT '';
+	ALTER TABLE endpoints ADD COLUMN file TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE endpoints ADD COLUMN json TEXT NOT NULL DEFAULT 'null'`,
+	`ALTER TABLE endpoints ADD COLUMN cookies TEXT NOT NULL DEFAULT 'null'`,
+	`ALTER TABLE endpoints ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE endpoints ADD COLUMN max_hits INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE endpoints ADD COLUMN namespace TEXT NOT NULL DEFAULT '';
+	CREATE INDEX endpoints_namespace_verb ON endpoints (namespace, verb);
+	CREATE TABLE namespaces (name TEXT PRIMARY KEY)`,
+	`ALTER TABLE endpoints ADD COLUMN source TEXT NOT NULL DEFAULT ''`,
+}
 
-const seedDB = `INSERT INTO endpoints (
-  type, verb, path, code, headers, body
-)
-VALUES
-  (
-    'endpoints', 'GET', '/revert_entropy', 200,
-    '{"Content-Type": "application/json"}',
-    '"{ "message": "INSUFFICIENT DATA FOR MEANINGFUL ANSWER" }"'
-  ),
-  (
-    'endpoints', 'POST', '/post_it', 201,
-    '{"Accept": "test/plain", "x-api-key":"superdupersecret"}',
-    'Your secrets are not so safe'
-  ),
-  (
-    'endpoints', 'PUT', '/fail', 400,
-    '{"Accept": "test/plain", "Content-Type": "application/json"}',
-    '"{"error": "something went horribly wrong :(" }"'
-  ),
-  (
-    'endpoints', 'DELETE', '/fake_delete',
-    204, '{}',
-    ''
-  )`
+// upgrades convert the existing data when SQL alone can't. Each one runs
+// right after the migration with the same index, in its transaction.
+var upgrades = map[int]func(tx *sql.Tx) error{
+	6: unquoteStoredBodies,
+}
+
+// endpointColumns is the order in which scanEndpoint reads an endpoint and,
+// but for the id, the order of the values returned by columnValues.
+const endpointColumns = "id, type, verb, path, code, headers, body, template, matchers, priority, sequence, scenario, delay, fault, encoding, file, json, cookies, expires_at, max_hits, namespace, source"
 
 const (
-	createEndpointQuery = `INSERT INTO endpoints ( verb, path, code, headers, body, type ) VALUES ( ?, ?, ?, ?, ?, ? ) RETURNING *`
-	updateEndpointQuery = `UPDATE endpoints SET type = ?, verb = ?, path = ?, code = ?, headers = ?, body = ? WHERE id = ? RETURNING *`
-	fetchEndpointsQuery = "SELECT * FROM endpoints ORDER by id"
-	deleteEndpointQuery = "DELETE FROM endpoints WHERE id = ?"
-	findEndpointQuery   = "SELECT code, headers, body FROM endpoints WHERE verb = ? AND path = ?"
+	createEndpointQuery      = `INSERT INTO endpoints ( type, verb, path, code, headers, body, template, matchers, priority, sequence, scenario, delay, fault, encoding, file, json, cookies, expires_at, max_hits, namespace, source ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? ) RETURNING ` + endpointColumns
+	updateEndpointQuery      = `UPDATE endpoints SET type = ?, verb = ?, path = ?, code = ?, headers = ?, body = ?, template = ?, matchers = ?, priority = ?, sequence = ?, scenario = ?, delay = ?, fault = ?, encoding = ?, file = ?, json = ?, cookies = ?, expires_at = ?, max_hits = ?, namespace = ?, source = ? WHERE id = ? RETURNING ` + endpointColumns
+	fetchEndpointsQuery      = "SELECT " + endpointColumns + " FROM endpoints ORDER by id"
+	deleteEndpointQuery      = "DELETE FROM endpoints WHERE id = ?"
+	deleteExpiredQuery       = "DELETE FROM endpoints WHERE expires_at > 0 AND expires_at <= ? RETURNING id"
+	findEndpointQuery        = "SELECT " + endpointColumns + " FROM endpoints WHERE namespace = ? AND verb = ? ORDER BY id"
+	endpointInNamespaceQuery = "SELECT EXISTS (SELECT 1 FROM endpoints WHERE namespace = ?)"
+	createNamespaceQuery     = "INSERT INTO namespaces (name) VALUES (?) ON CONFLICT DO NOTHING"
+	fetchNamespacesQuery     = `SELECT name, SUM(n) FROM (
+  SELECT name, 0 AS n FROM namespaces UNION ALL SELECT namespace, 1 FROM endpoints WHERE namespace != ''
+) GROUP BY name ORDER BY name`
+	deleteNamespaceEndpointsQuery = "DELETE FROM endpoints WHERE namespace = ? RETURNING id"
+	deleteNamespaceQuery          = "DELETE FROM namespaces WHERE name = ?"
 )
 
 type One struct {
@@ -63,23 +85,139 @@ type Endpoint struct {
 }
 
 type Attributes struct {
-	Verb     string   `json:"verb" validate:"required,oneof=GET HEAD OPTIONS TRACE PUT DELETE POST PATCH CONNECT"`
-	Path     string   `json:"path" validate:"required,uri"`
-	Response Response `json:"response" validate:"required"`
+	// Namespace is set by the server to the namespace of the client that
+	// creates the endpoint. Empty is the default namespace.
+	Namespace string `json:"namespace,omitempty" validate:"omitempty,namespace"`
+	// Source is set by the server to the definition file the endpoint was
+	// loaded from, relative to the mocks directory. It's empty for the
+	// endpoints created through the API.
+	Source   string    `json:"source,omitempty"`
+	Verb     string    `json:"verb" validate:"required,oneof=GET HEAD OPTIONS TRACE PUT DELETE POST PATCH CONNECT"`
+	Path     string    `json:"path" validate:"required,uri,pathtemplate"`
+	Response Response  `json:"response" validate:"required_without=Sequence,excluded_with=Sequence,omitempty"`
+	Match    *Matchers `json:"match,omitempty"`
+	// Priority breaks ties between endpoints matching the same request.
+	Priority int `json:"priority,omitempty"`
+	// Sequence replaces Response with a list of responses.
+	Sequence *Sequence `json:"sequence,omitempty"`
+	Scenario *Scenario `json:"scenario,omitempty"`
+	Delay    *Delay    `json:"delay,omitempty"`
+	Fault    *Fault    `json:"fault,omitempty"`
+	// TTL is the lifetime of the endpoint in seconds. It's turned into
+	// ExpiresAt when the endpoint is created or updated, see SetExpiry.
+	TTL       int        `json:"ttl,omitempty" validate:"omitempty,min=1,excluded_with=ExpiresAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// MaxHits is the number of requests the endpoint serves before it's
+	// deleted.
+	MaxHits int `json:"maxHits,omitempty" validate:"omitempty,min=1"`
 }
 
 type Response struct {
-	Code    int               `json:"code" validate:"required,gte=100,lte=599"`
-	Headers map[string]string `json:"headers"`
-	Body    string            `json:"body"`
+	Code    int     `json:"code" validate:"required,gte=100,lte=599"`
+	Headers Headers `json:"headers"`
+	// Cookies are written as Set-Cookie headers, after Headers.
+	Cookies []Cookie `json:"cookies,omitempty" validate:"omitempty,dive"`
+	Body    string   `json:"body"`
+	// JSON is served instead of Body, as application/json unless there's a
+	// Content-Type header.
+	JSON JSONValue `json:"json,omitempty"`
+	// Template enables rendering Body and the Headers values as Go templates
+	// with data from the incoming request.
+	Template bool `json:"template,omitempty"`
+	// Encoding sets how Body is served. Bodies without an encoding are served
+	// as they are.
+	Encoding Encoding `json:"encoding,omitempty" validate:"omitempty,oneof=text json base64"`
+	// File is the path of a file, relative to the fixtures directory, served
+	// as the body instead of Body.
+	File string `json:"file,omitempty" validate:"omitempty,fixture"`
+	// Weight is the share of requests served by the response in round-robin
+	// and weighted-random sequences. It defaults to 1.
+	Weight int `json:"weight,omitempty" validate:"omitempty,min=1"`
 }
 
+// Storage is the set of operations the server needs from a backend to
+// manage and serve mock endpoints.
+type Storage interface {
+	Seed() error
+	FetchEndpoints() (*Many, error)
+	FindEndpoint(r *Request) (*Match, error)
+	CreateEndpoint(endpoint *Endpoint) (*One, error)
+	UpdateEndpoint(id string, endpoint *Endpoint) (*One, error)
+	DeleteEndpoint(id string) (bool, error)
+	// DeleteExpired deletes the endpoints that have expired by now and
+	// returns their ids.
+	DeleteExpired(now time.Time) ([]int, error)
+	// CreateNamespace creates an empty namespace. It returns false when the
+	// namespace already exists.
+	CreateNamespace(name string) (bool, error)
+	// FetchNamespaces returns the namespaces that were created or hold
+	// endpoints, sorted by name.
+	FetchNamespaces() ([]*Namespace, error)
+	// DeleteNamespace deletes the namespace along with its endpoints and
+	// returns their ids. It returns false when the namespace doesn't exist.
+	DeleteNamespace(name string) ([]int, bool, error)
+	Close() error
+}
+
+// seed returns the example endpoints every backend loads on Seed.
+func seed() []*Endpoint {
+	return []*Endpoint{
+		{
+			Type: "endpoints",
+			Attributes: Attributes{
+				Verb: "GET", Path: "/revert_entropy",
+				Response: Response{
+					Code:    200,
+					Headers: Headers{"Content-Type": {"application/json"}},
+					JSON:    JSONValue(`{"message":"INSUFFICIENT DATA FOR MEANINGFUL ANSWER"}`),
+				},
+			},
+		},
+		{
+			Type: "endpoints",
+			Attributes: Attributes{
+				Verb: "POST", Path: "/post_it",
+				Response: Response{
+					Code:    201,
+					Headers: Headers{"Accept": {"test/plain"}, "x-api-key": {"superdupersecret"}},
+					Body:    "Your secrets are not so safe",
+				},
+			},
+		},
+		{
+			Type: "endpoints",
+			Attributes: Attributes{
+				Verb: "PUT", Path: "/fail",
+				Response: Response{
+					Code:    400,
+					Headers: Headers{"Accept": {"test/plain"}, "Content-Type": {"application/json"}},
+					JSON:    JSONValue(`{"error":"something went horribly wrong :("}`),
+				},
+			},
+		},
+		{
+			Type: "endpoints",
+			Attributes: Attributes{
+				Verb: "DELETE", Path: "/fake_delete",
+				Response: Response{
+					Code:    204,
+					Headers: Headers{},
+				},
+			},
+		},
+	}
+}
+
+// Store is the SQLite backed Storage.
 type Store struct {
 	db *sql.DB
 }
 
-func New() (*Store, error) {
-	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+// New opens the SQLite database described by dsn and migrates it to the
+// latest schema version. dsn can be a plain file path or a SQLite DSN
+// (`file:...`). An empty dsn opens an in-memory database.
+func New(dsn string) (*Store, error) {
+	db, err := sql.Open("sqlite3", parseDSN(dsn))
 	if err != nil {
 		return nil, fmt.Errorf("unable to open DB: %v", err)
 	}
@@ -88,55 +226,142 @@ func New() (*Store, error) {
 		return nil, fmt.Errorf("unable to ping DB: %v", err)
 	}
 
-	if _, err := db.Exec(dbSchema); err != nil {
-		return nil, fmt.Errorf("unable to create tables: %v", err)
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to migrate DB: %v", err)
 	}
 
 	return &Store{db: db}, nil
 }
 
-func (s *Store) Close() error {
-	return s.db.Close()
+// parseDSN turns dsn into a SQLite DSN that waits up to 5 seconds for a
+// locked database, unless dsn already sets its own busy timeout.
+func parseDSN(dsn string) string {
+	if dsn == "" {
+		return memoryDSN
+	}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+
+	_, query, found := strings.Cut(dsn, "?")
+	if !found {
+		return dsn + "?_busy_timeout=5000"
+	}
+	params, _ := url.ParseQuery(query)
+	if params.Has("_busy_timeout") || params.Has("_timeout") {
+		return dsn
+	}
+	return dsn + "&_busy_timeout=5000"
 }
 
-func (s *Store) Seed() error {
-	if _, err := s.db.Exec(seedDB); err != nil {
-		return fmt.Errorf("unable to seed db: %v", err)
+// migrate applies, in order, every migration newer than the database's
+// current schema version. Each migration runs in its own transaction together
+// with the version bump, so a failure leaves the database at the last good
+// version.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if upgrade, ok := upgrades[i]; ok {
+			if err := upgrade(tx); err != nil {
+				tx.Rollback() //nolint:errcheck
+				return fmt.Errorf("migration %d: %v", i+1, err)
+			}
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
 	}
+
 	return nil
 }
 
-func (s *Store) FetchEndpoints() (*Many, error) {
-	rows, err := s.db.Query(fetchEndpointsQuery)
+// unquoteStoredBodies converts the bodies saved when their surrounding quotes
+// were removed before serving them, see UnquoteBodies. It only reads the
+// columns that existed at the time.
+func unquoteStoredBodies(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, body, template, sequence, encoding, file FROM endpoints")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-	data := []*Endpoint{}
+	endpoints := []*Endpoint{}
 	for rows.Next() {
 		e := &Endpoint{}
-		var headers string
-		if err := rows.Scan(
-			&e.ID,
-			&e.Type,
-			&e.Attributes.Verb,
-			&e.Attributes.Path,
-			&e.Attributes.Response.Code,
-			&headers,
-			&e.Attributes.Response.Body,
-		); err != nil {
-			return nil, err
+		r := &e.Attributes.Response
+		var sequence string
+		if err := rows.Scan(&e.ID, &r.Body, &r.Template, &sequence, &r.Encoding, &r.File); err != nil {
+			rows.Close() //nolint:errcheck
+			return err
 		}
-		if err = json.Unmarshal([]byte(headers), &e.Attributes.Response.Headers); err != nil {
-			return nil, err
+		if err := json.Unmarshal([]byte(sequence), &e.Attributes.Sequence); err != nil {
+			rows.Close() //nolint:errcheck
+			return err
 		}
-
-		data = append(data, e)
+		endpoints = append(endpoints, e)
 	}
 	if err := rows.Close(); err != nil {
-		return nil, err
+		return err
 	}
 	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range endpoints {
+		UnquoteBodies(e)
+		sequence, err := json.Marshal(e.Attributes.Sequence)
+		if err != nil {
+			return err
+		}
+		jsonBody, err := json.Marshal(e.Attributes.Response.JSON)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE endpoints SET body = ?, json = ?, sequence = ? WHERE id = ?",
+			e.Attributes.Response.Body, string(jsonBody), string(sequence), e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Seed creates the built-in endpoints, skipping those that already exist.
+func (s *Store) Seed() error {
+	if _, _, err := CreateEndpoints(s, seed()); err != nil {
+		return fmt.Errorf("unable to seed db: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) FetchEndpoints() (*Many, error) {
+	rows, err := s.db.Query(fetchEndpointsQuery)
+	if err != nil {
+		return nil, err
+	}
+	data, err := scanEndpoints(rows)
+	if err != nil {
 		return nil, err
 	}
 
@@ -144,35 +369,17 @@ func (s *Store) FetchEndpoints() (*Many, error) {
 }
 
 func (s *Store) CreateEndpoint(endpoint *Endpoint) (*One, error) {
-	h, err := json.Marshal(endpoint.Attributes.Response.Headers)
+	values, err := columnValues(endpoint)
 	if err != nil {
 		return nil, err
 	}
-	row := s.db.QueryRow(createEndpointQuery,
-		endpoint.Attributes.Verb,
-		endpoint.Attributes.Path,
-		endpoint.Attributes.Response.Code,
-		string(h),
-		endpoint.Attributes.Response.Body,
-		endpoint.Type,
-	)
-	e := &Endpoint{}
-	var headers string
-	err = row.Scan(
-		&e.ID,
-		&e.Type,
-		&e.Attributes.Verb,
-		&e.Attributes.Path,
-		&e.Attributes.Response.Code,
-		&headers,
-		&e.Attributes.Response.Body,
-	)
+	row := s.db.QueryRow(createEndpointQuery, values...)
+	e, err := scanEndpoint(row)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal([]byte(headers), &e.Attributes.Response.Headers)
 
-	return &One{Data: e}, err
+	return &One{Data: e}, nil
 }
 
 func (s *Store) DeleteEndpoint(id string) (bool, error) {
@@ -191,53 +398,228 @@ func (s *Store) DeleteEndpoint(id string) (bool, error) {
 	return true, nil
 }
 
-func (s *Store) FindEndpoint(verb, path string) (*Response, error) {
-	row := s.db.QueryRow(findEndpointQuery, verb, path)
-	r := &Response{}
-	var headers string
-	if err := row.Scan(&r.Code, &headers, &r.Body); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+func (s *Store) DeleteExpired(now time.Time) ([]int, error) {
+	rows, err := s.db.Query(deleteExpiredQuery, now.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *Store) CreateNamespace(name string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after Commit
+
+	var exists bool
+	if err := tx.QueryRow(endpointInNamespaceQuery, name).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	result, err := tx.Exec(createNamespaceQuery, name)
+	if err != nil {
+		return false, err
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return created > 0, tx.Commit()
+}
+
+func (s *Store) FetchNamespaces() ([]*Namespace, error) {
+	rows, err := s.db.Query(fetchNamespacesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	namespaces := []*Namespace{}
+	for rows.Next() {
+		ns := &Namespace{}
+		if err := rows.Scan(&ns.Name, &ns.Endpoints); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
+
+func (s *Store) DeleteNamespace(name string) ([]int, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after Commit
+
+	rows, err := tx.Query(deleteNamespaceEndpointsQuery, name)
+	if err != nil {
+		return nil, false, err
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close() //nolint:errcheck
+			return nil, false, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, false, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	result, err := tx.Exec(deleteNamespaceQuery, name)
+	if err != nil {
+		return nil, false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	return ids, deleted > 0 || len(ids) > 0, tx.Commit()
+}
+
+func (s *Store) FindEndpoint(r *Request) (*Match, error) {
+	rows, err := s.db.Query(findEndpointQuery, r.Namespace, r.Method)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := scanEndpoints(rows)
+	if err != nil {
 		return nil, err
 	}
-	err := json.Unmarshal([]byte(headers), &r.Headers)
 
-	return r, err
+	return find(candidates, r), nil
 }
 
 func (s *Store) UpdateEndpoint(id string, endpoint *Endpoint) (*One, error) {
-	h, err := json.Marshal(endpoint.Attributes.Response.Headers)
+	values, err := columnValues(endpoint)
 	if err != nil {
 		return nil, err
 	}
-	row := s.db.QueryRow(updateEndpointQuery,
-		endpoint.Type,
-		endpoint.Attributes.Verb,
-		endpoint.Attributes.Path,
-		endpoint.Attributes.Response.Code,
-		string(h),
-		endpoint.Attributes.Response.Body,
-		id,
-	)
+	row := s.db.QueryRow(updateEndpointQuery, append(values, id)...)
+	e, err := scanEndpoint(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &One{Data: e}, nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// columnValues returns the values of the endpoint's columns, but for the id,
+// in the order of endpointColumns. Nested attributes are stored as JSON.
+func columnValues(e *Endpoint) ([]any, error) {
+	a := &e.Attributes
+	values := []any{
+		e.Type, a.Verb, a.Path, a.Response.Code, a.Response.Headers, a.Response.Body,
+		a.Response.Template, a.Match, a.Priority, a.Sequence, a.Scenario, a.Delay, a.Fault,
+		string(a.Response.Encoding), a.Response.File, a.Response.JSON, a.Response.Cookies,
+		expiresAtColumn(a), a.MaxHits, a.Namespace, a.Source,
+	}
+	for i, v := range values {
+		switch v.(type) {
+		case string, int, int64, bool:
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(b)
+	}
+	return values, nil
+}
+
+// scanEndpoint reads an endpoint from a row selected with endpointColumns.
+func scanEndpoint(row scanner) (*Endpoint, error) {
 	e := &Endpoint{}
-	var headers string
-	err = row.Scan(
+	a := &e.Attributes
+	var headers, matchers, sequence, scenario, delay, fault, jsonBody, cookies string
+	var expiresAt int64
+	if err := row.Scan(
 		&e.ID,
 		&e.Type,
-		&e.Attributes.Verb,
-		&e.Attributes.Path,
-		&e.Attributes.Response.Code,
+		&a.Verb,
+		&a.Path,
+		&a.Response.Code,
 		&headers,
-		&e.Attributes.Response.Body,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		&a.Response.Body,
+		&a.Response.Template,
+		&matchers,
+		&a.Priority,
+		&sequence,
+		&scenario,
+		&delay,
+		&fault,
+		&a.Response.Encoding,
+		&a.Response.File,
+		&jsonBody,
+		&cookies,
+		&expiresAt,
+		&a.MaxHits,
+		&a.Namespace,
+		&a.Source,
+	); err != nil {
+		return nil, err
+	}
+	a.ExpiresAt = scanExpiresAt(expiresAt)
+	for col, v := range map[*string]any{
+		&headers:  &a.Response.Headers,
+		&matchers: &a.Match,
+		&sequence: &a.Sequence,
+		&scenario: &a.Scenario,
+		&delay:    &a.Delay,
+		&fault:    &a.Fault,
+		&jsonBody: &a.Response.JSON,
+		&cookies:  &a.Response.Cookies,
+	} {
+		if err := json.Unmarshal([]byte(*col), v); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func scanEndpoints(rows *sql.Rows) ([]*Endpoint, error) {
+	defer rows.Close()
+	data := []*Endpoint{}
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
 		}
+		data = append(data, e)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal([]byte(headers), &e.Attributes.Response.Headers)
 
-	return &One{Data: e}, err
+	return data, nil
 }
@@ -1,15 +1,19 @@
 package store
 
 import (
+	"database/sql"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestEndpointsVerify(t *testing.T) {
-	validate := validator.New()
+	validate := NewValidator()
 	tests := []struct {
 		name      string
 		wantNoErr bool
@@ -44,6 +48,29 @@ func TestEndpointsVerify(t *testing.T) {
 			name:   "empty path attribute",
 			modify: func(e *Endpoint) { e.Attributes.Path = "" },
 		},
+		{
+			name:      "path template",
+			wantNoErr: true,
+			modify:    func(e *Endpoint) { e.Attributes.Path = "/users/{id}/orders/{orderId}/*" },
+		},
+		{
+			name:   "wildcard in the middle of the path",
+			modify: func(e *Endpoint) { e.Attributes.Path = "/static/*/file" },
+		},
+		{
+			name:   "repeated path param",
+			modify: func(e *Endpoint) { e.Attributes.Path = "/users/{id}/orders/{id}" },
+		},
+		{
+			name: "invalid header regexp",
+			modify: func(e *Endpoint) {
+				e.Attributes.Match = &Matchers{Headers: map[string]ValueMatcher{"Accept": {Matches: "("}}}
+			},
+		},
+		{
+			name:   "invalid JSONPath",
+			modify: func(e *Endpoint) { e.Attributes.Match = &Matchers{Body: &BodyMatcher{JSONPath: "items"}} },
+		},
 		{
 			name:   "incorrect code attribute",
 			modify: func(e *Endpoint) { e.Attributes.Response.Code = 600 },
@@ -52,6 +79,170 @@ func TestEndpointsVerify(t *testing.T) {
 			name:   "empty code attribute",
 			modify: func(e *Endpoint) { e.Attributes.Response.Code = 0 },
 		},
+		{
+			name:      "sequence instead of a response",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Response = Response{}
+				e.Attributes.Sequence = &Sequence{Mode: Cycle, Responses: []Response{{Code: 202}, {Code: 200, Weight: 2}}}
+			},
+		},
+		{
+			name:   "response and sequence",
+			modify: func(e *Endpoint) { e.Attributes.Sequence = &Sequence{Responses: []Response{{Code: 202}}} },
+		},
+		{
+			name: "unknown sequence mode",
+			modify: func(e *Endpoint) {
+				e.Attributes.Sequence = &Sequence{Mode: "shuffle", Responses: []Response{{Code: 200}}}
+			},
+		},
+		{
+			name:   "empty sequence",
+			modify: func(e *Endpoint) { e.Attributes.Sequence = &Sequence{} },
+		},
+		{
+			name: "invalid response in sequence",
+			modify: func(e *Endpoint) {
+				e.Attributes.Sequence = &Sequence{Responses: []Response{{Code: 200}, {Code: 0}}}
+			},
+		},
+		{
+			name:      "delay and fault",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Delay = &Delay{Distribution: Uniform, Min: 10, Max: 20}
+				e.Attributes.Fault = &Fault{Type: ThrottleFault, BytesPerSecond: 1024}
+			},
+		},
+		{
+			name:   "unknown delay distribution",
+			modify: func(e *Endpoint) { e.Attributes.Delay = &Delay{Distribution: "poisson", Milliseconds: 10} },
+		},
+		{
+			name:   "uniform delay with max below min",
+			modify: func(e *Endpoint) { e.Attributes.Delay = &Delay{Distribution: Uniform, Min: 20, Max: 10} },
+		},
+		{
+			name: "fault probability above 1",
+			modify: func(e *Endpoint) {
+				p := 1.5
+				e.Attributes.Fault = &Fault{Type: ErrorFault, Probability: &p}
+			},
+		},
+		{
+			name:   "throttle fault without bandwidth",
+			modify: func(e *Endpoint) { e.Attributes.Fault = &Fault{Type: ThrottleFault} },
+		},
+		{
+			name:      "encoded and file bodies",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Response = Response{}
+				e.Attributes.Sequence = &Sequence{Responses: []Response{
+					{Code: 200, Encoding: Base64Encoding, Body: "iVBORw0KGgo="},
+					{Code: 200, Encoding: JSONEncoding, Body: `{"ok":true}`},
+					{Code: 200, File: "images/logo.png"},
+				}}
+			},
+		},
+		{
+			name:   "invalid base64 body",
+			modify: func(e *Endpoint) { e.Attributes.Response.Encoding, e.Attributes.Response.Body = Base64Encoding, "%%%" },
+		},
+		{
+			name:   "invalid JSON body",
+			modify: func(e *Endpoint) { e.Attributes.Response.Encoding, e.Attributes.Response.Body = JSONEncoding, "{" },
+		},
+		{
+			name:   "file and body",
+			modify: func(e *Endpoint) { e.Attributes.Response.File, e.Attributes.Response.Body = "logo.png", "logo" },
+		},
+		{
+			name:   "file outside the fixtures",
+			modify: func(e *Endpoint) { e.Attributes.Response.File = "../logo.png" },
+		},
+		{
+			name:      "JSON body",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Response.Body, e.Attributes.Response.JSON = "", JSONValue(`{"ok":true}`)
+			},
+		},
+		{
+			name: "JSON and body",
+			modify: func(e *Endpoint) {
+				e.Attributes.Response.Body, e.Attributes.Response.JSON = "ok", JSONValue(`{"ok":true}`)
+			},
+		},
+		{
+			name: "templated JSON",
+			modify: func(e *Endpoint) {
+				e.Attributes.Response.Body, e.Attributes.Response.Template = "", true
+				e.Attributes.Response.JSON = JSONValue(`{"ok":true}`)
+			},
+		},
+		{
+			name:      "multi-value headers and cookies",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Response.Headers = Headers{"Vary": {"Accept", "Origin"}}
+				e.Attributes.Response.Cookies = []Cookie{{Name: "a", Value: "1", SameSite: SameSiteLax}, {Name: "b", HttpOnly: true}}
+			},
+		},
+		{
+			name:      "templated cookie values",
+			wantNoErr: true,
+			modify: func(e *Endpoint) {
+				e.Attributes.Response.Template = true
+				e.Attributes.Response.Cookies = []Cookie{{Name: "id", Value: `{{index .Headers "X-Id"}}`}}
+			},
+		},
+		{
+			name:   "cookie without a name",
+			modify: func(e *Endpoint) { e.Attributes.Response.Cookies = []Cookie{{Value: "1"}} },
+		},
+		{
+			name:   "invalid cookie name",
+			modify: func(e *Endpoint) { e.Attributes.Response.Cookies = []Cookie{{Name: "a b"}} },
+		},
+		{
+			name:   "invalid cookie value",
+			modify: func(e *Endpoint) { e.Attributes.Response.Cookies = []Cookie{{Name: "a", Value: `"{{.Path}}"x`}} },
+		},
+		{
+			name:   "invalid cookie same site",
+			modify: func(e *Endpoint) { e.Attributes.Response.Cookies = []Cookie{{Name: "a", SameSite: "Loose"}} },
+		},
+		{
+			name:      "ttl and max hits",
+			wantNoErr: true,
+			modify:    func(e *Endpoint) { e.Attributes.TTL, e.Attributes.MaxHits = 60, 3 },
+		},
+		{
+			name: "ttl and expiry time",
+			modify: func(e *Endpoint) {
+				expires := time.Now()
+				e.Attributes.TTL, e.Attributes.ExpiresAt = 60, &expires
+			},
+		},
+		{
+			name:   "negative max hits",
+			modify: func(e *Endpoint) { e.Attributes.MaxHits = -1 },
+		},
+		{
+			name:   "scenario without a name",
+			modify: func(e *Endpoint) { e.Attributes.Scenario = &Scenario{RequiredState: "paid"} },
+		},
+		{
+			name:      "namespace",
+			wantNoErr: true,
+			modify:    func(e *Endpoint) { e.Attributes.Namespace = "ci-job_42.a" },
+		},
+		{
+			name:   "namespace with a slash",
+			modify: func(e *Endpoint) { e.Attributes.Namespace = "ci/42" },
+		},
 	}
 
 	for _, test := range tests {
@@ -67,11 +258,30 @@ func TestEndpointsVerify(t *testing.T) {
 	}
 }
 
+// backends lists every Storage implementation. All of them must pass the
+// conformance suite in TestStorage.
+var backends = map[string]func(t *testing.T) Storage{
+	"sqlite": func(t *testing.T) Storage {
+		s, err := New("")
+		assert.NoError(t, err)
+		return s
+	},
+	"memory": func(*testing.T) Storage {
+		return NewMemory()
+	},
+}
+
 func TestStorage(t *testing.T) {
-	store, err := New()
-	assert.NoError(t, err)
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			testStorage(t, newStorage(t))
+		})
+	}
+}
+
+func testStorage(t *testing.T, store Storage) {
 	defer store.Close()
-	err = store.Seed()
+	err := store.Seed()
 	assert.NoError(t, err)
 
 	testEndpoint := newTestEndpoint()
@@ -80,6 +290,11 @@ func TestStorage(t *testing.T) {
 		assertLenEndpoints(t, 4, store)
 	})
 
+	t.Run("Seed skips the endpoints that exist", func(t *testing.T) {
+		assert.NoError(t, store.Seed())
+		assertLenEndpoints(t, 4, store)
+	})
+
 	t.Run("CreateEndpoint creates a new endpoint", func(t *testing.T) {
 		created, err := store.CreateEndpoint(testEndpoint)
 		assert.NoError(t, err)
@@ -114,24 +329,172 @@ func TestStorage(t *testing.T) {
 		assertLenEndpoints(t, 4, store)
 	})
 
+	t.Run("UpdateEndpoint and DeleteEndpoint ignore ids that are not numeric", func(t *testing.T) {
+		updated, err := store.UpdateEndpoint("abc", testEndpoint)
+		assert.NoError(t, err)
+		assert.Nil(t, updated)
+		ok, err := store.DeleteEndpoint("abc")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
 	t.Run("FindEndpoint finds an endpoint by given Verb and Path", func(t *testing.T) {
-		e, err := store.FindEndpoint(http.MethodGet, "/revert_entropy")
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/revert_entropy"})
 		assert.NoError(t, err)
 
-		assert.NotNil(t, e)
+		assert.NotNil(t, m)
+		e := m.Endpoint.Attributes.Response
 		assert.Equal(t, http.StatusOK, e.Code)
-		assert.Equal(t, map[string]string{"Content-Type": "application/json"}, e.Headers)
-		assert.Equal(t, `"{ "message": "INSUFFICIENT DATA FOR MEANINGFUL ANSWER" }"`, e.Body)
+		assert.Equal(t, Headers{"Content-Type": {"application/json"}}, e.Headers)
+		assert.Equal(t, JSONValue(`{"message":"INSUFFICIENT DATA FOR MEANINGFUL ANSWER"}`), e.JSON)
+	})
+
+	t.Run("FindEndpoint matches path templates and captures their params", func(t *testing.T) {
+		template := newTestEndpoint()
+		template.Attributes.Path = "/users/{id}/orders/{orderId}"
+		created, err := store.CreateEndpoint(template)
+		assert.NoError(t, err)
+
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/users/42/orders/7"})
+		assert.NoError(t, err)
+		assert.NotNil(t, m)
+		assert.Equal(t, created.Data, m.Endpoint)
+		assert.Equal(t, map[string]string{"id": "42", "orderId": "7"}, m.Params)
+
+		ok, err := store.DeleteEndpoint(fmt.Sprint(created.Data.ID))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("FindEndpoint uses the endpoint matchers", func(t *testing.T) {
+		foo, bar := newTestEndpoint(), newTestEndpoint()
+		foo.Attributes.Path, bar.Attributes.Path = "/search", "/search"
+		foo.Attributes.Match = &Matchers{Query: map[string]ValueMatcher{"q": {Equals: "foo"}}}
+		bar.Attributes.Match = &Matchers{Body: &BodyMatcher{JSON: map[string]any{"q": "bar"}}}
+		bar.Attributes.Priority = 2
+		for _, e := range []*Endpoint{foo, bar} {
+			created, err := store.CreateEndpoint(e)
+			assert.NoError(t, err)
+			assert.Equal(t, e.Attributes, created.Data.Attributes)
+			defer store.DeleteEndpoint(fmt.Sprint(created.Data.ID)) //nolint:errcheck
+		}
+
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/search", Query: url.Values{"q": {"foo"}}})
+		assert.NoError(t, err)
+		assert.Equal(t, foo.Attributes.Match, m.Endpoint.Attributes.Match)
+		m, err = store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/search", Body: []byte(`{"q":"bar"}`)})
+		assert.NoError(t, err)
+		assert.Equal(t, bar.Attributes.Match, m.Endpoint.Attributes.Match)
+		m, err = store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/search"})
+		assert.NoError(t, err)
+		assert.Nil(t, m)
+	})
+
+	t.Run("FindEndpoint uses the scenario states and keeps every attribute", func(t *testing.T) {
+		e := newTestEndpoint()
+		e.Attributes.Path = "/order"
+		e.Attributes.Sequence = &Sequence{Mode: RoundRobin, Responses: []Response{{Code: 202}, {Code: 200, Weight: 3}}}
+		e.Attributes.Scenario = &Scenario{Name: "checkout", RequiredState: "paid", NewState: "shipped"}
+		e.Attributes.Delay = &Delay{Distribution: LogNormal, Median: 50, Sigma: 0.3}
+		e.Attributes.Fault = &Fault{Type: TruncateFault}
+		e.Attributes.Response.Encoding, e.Attributes.Response.File = JSONEncoding, "orders/1.json"
+		expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		e.Attributes.Response.Headers = Headers{"Vary": {"Accept", "Origin"}}
+		e.Attributes.Response.Cookies = []Cookie{{Name: "order", Value: "1", Expires: &expires, SameSite: SameSiteLax}}
+		e.Attributes.ExpiresAt, e.Attributes.MaxHits = &expires, 5
+		e.Attributes.Source = "orders.yaml"
+		created, err := store.CreateEndpoint(e)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Attributes, created.Data.Attributes)
+		defer store.DeleteEndpoint(fmt.Sprint(created.Data.ID)) //nolint:errcheck
+
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/order"})
+		assert.NoError(t, err)
+		assert.Nil(t, m)
+		m, err = store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/order", Scenarios: map[string]string{"checkout": "paid"}})
+		assert.NoError(t, err)
+		assert.Equal(t, created.Data, m.Endpoint)
+	})
+
+	t.Run("expired endpoints don't match and DeleteExpired deletes them", func(t *testing.T) {
+		now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		e := newTestEndpoint()
+		e.Attributes.Path = "/ephemeral"
+		e.Attributes.ExpiresAt = &now
+		created, err := store.CreateEndpoint(e)
+		assert.NoError(t, err)
+
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/ephemeral", Time: now.Add(-time.Second)})
+		assert.NoError(t, err)
+		assert.NotNil(t, m)
+		m, err = store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/ephemeral", Time: now})
+		assert.NoError(t, err)
+		assert.Nil(t, m)
+
+		ids, err := store.DeleteExpired(now.Add(-time.Second))
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+		ids, err = store.DeleteExpired(now)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{created.Data.ID}, ids)
+		ok, err := store.DeleteEndpoint(fmt.Sprint(created.Data.ID))
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("namespaces isolate their endpoints", func(t *testing.T) {
+		e := newTestEndpoint()
+		e.Attributes.Namespace = "ci-1"
+		created, err := store.CreateEndpoint(e)
+		assert.NoError(t, err)
+		assert.Equal(t, "ci-1", created.Data.Attributes.Namespace)
+
+		m, err := store.FindEndpoint(&Request{Namespace: "ci-1", Method: http.MethodGet, Path: "/hello"})
+		assert.NoError(t, err)
+		assert.Equal(t, created.Data, m.Endpoint)
+		m, err = store.FindEndpoint(&Request{Namespace: "ci-2", Method: http.MethodGet, Path: "/hello"})
+		assert.NoError(t, err)
+		assert.Nil(t, m)
+
+		ok, err := store.CreateNamespace("ci-1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = store.CreateNamespace("ci-2")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = store.CreateNamespace("ci-2")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		ns, err := store.FetchNamespaces()
+		assert.NoError(t, err)
+		assert.Equal(t, []*Namespace{{Name: "ci-1", Endpoints: 1}, {Name: "ci-2"}}, ns)
+
+		ids, ok, err := store.DeleteNamespace("ci-1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []int{created.Data.ID}, ids)
+		ids, ok, err = store.DeleteNamespace("ci-2")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Empty(t, ids)
+		_, ok, err = store.DeleteNamespace("ci-3")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		ns, err = store.FetchNamespaces()
+		assert.NoError(t, err)
+		assert.Empty(t, ns)
 	})
 
 	t.Run("FindEndpoint returns nil when not finding and enpoint", func(t *testing.T) {
-		e, err := store.FindEndpoint(http.MethodGet, "/noluck")
+		e, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/noluck"})
 		assert.NoError(t, err)
 		assert.Nil(t, e)
 	})
 }
 
-func assertLenEndpoints(t testing.TB, want int, s *Store) {
+func assertLenEndpoints(t testing.TB, want int, s Storage) {
 	e, err := s.FetchEndpoints()
 	assert.NoError(t, err)
 	assert.Equal(t, want, len(e.Data))
@@ -146,8 +509,122 @@ func newTestEndpoint() *Endpoint {
 			Path: "/hello",
 			Response: Response{
 				Code:    200,
-				Headers: map[string]string{"Accept": "application/json"},
+				Headers: Headers{"Accept": {"application/json"}},
 			},
 		},
 	}
 }
+
+func TestPersistentStorage(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "echo.db")
+
+	store, err := New(dsn)
+	assert.NoError(t, err)
+	created, err := store.CreateEndpoint(newTestEndpoint())
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	t.Run("endpoints survive reopening the database", func(t *testing.T) {
+		store, err := New(dsn)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		e, err := store.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Equal(t, []*Endpoint{created.Data}, e.Data)
+	})
+
+	t.Run("database is migrated to the latest version", func(t *testing.T) {
+		store, err := New(dsn)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		var version int
+		assert.NoError(t, store.db.QueryRow("PRAGMA user_version").Scan(&version))
+		assert.Equal(t, len(migrations), version)
+	})
+
+	t.Run("migrating keeps the existing data", func(t *testing.T) {
+		dsn := filepath.Join(t.TempDir(), "v1.db")
+		db, err := sql.Open("sqlite3", parseDSN(dsn))
+		assert.NoError(t, err)
+		_, err = db.Exec(migrations[0])
+		assert.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO endpoints (type, verb, path, code, headers, body) VALUES ('endpoints', 'GET', '/old', 200, '{}', 'old')`)
+		assert.NoError(t, err)
+		_, err = db.Exec("PRAGMA user_version = 1")
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+
+		store, err := New(dsn)
+		assert.NoError(t, err)
+		defer store.Close()
+		m, err := store.FindEndpoint(&Request{Method: http.MethodGet, Path: "/old"})
+		assert.NoError(t, err)
+		assert.NotNil(t, m)
+		assert.Equal(t, "old", m.Endpoint.Attributes.Response.Body)
+	})
+
+	t.Run("migrating removes the quotes bodies used to have", func(t *testing.T) {
+		dsn := filepath.Join(t.TempDir(), "v6.db")
+		db, err := sql.Open("sqlite3", parseDSN(dsn))
+		assert.NoError(t, err)
+		for _, m := range migrations[:6] {
+			_, err = db.Exec(m)
+			assert.NoError(t, err)
+		}
+		_, err = db.Exec(`INSERT INTO endpoints (type, verb, path, code, headers, body, sequence, encoding) VALUES
+			('endpoints', 'GET', '/json', 200, '{}', '"{ "message": "hi" }"', 'null', ''),
+			('endpoints', 'GET', '/text', 200, '{}', '"hi"', 'null', ''),
+			('endpoints', 'GET', '/encoded', 200, '{}', '"hi"', 'null', 'text'),
+			('endpoints', 'GET', '/sequence', 200, '{}', '', '{"responses":[{"code":200,"headers":null,"body":"\"[1, 2]\""}]}', '')`)
+		assert.NoError(t, err)
+		_, err = db.Exec("PRAGMA user_version = 6")
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+
+		store, err := New(dsn)
+		assert.NoError(t, err)
+		defer store.Close()
+		e, err := store.FetchEndpoints()
+		assert.NoError(t, err)
+		assert.Len(t, e.Data, 4)
+
+		assert.Equal(t, JSONValue(`{"message":"hi"}`), e.Data[0].Attributes.Response.JSON)
+		assert.Equal(t, "", e.Data[0].Attributes.Response.Body)
+		assert.Equal(t, "hi", e.Data[1].Attributes.Response.Body)
+		assert.Equal(t, `"hi"`, e.Data[2].Attributes.Response.Body)
+		assert.Equal(t, JSONValue(`[1,2]`), e.Data[3].Attributes.Sequence.Responses[0].JSON)
+	})
+
+	t.Run("opening a database newer than the supported schema fails", func(t *testing.T) {
+		store, err := New(dsn)
+		assert.NoError(t, err)
+		_, err = store.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations)+1))
+		assert.NoError(t, err)
+		assert.NoError(t, store.Close())
+
+		_, err = New(dsn)
+		assert.ErrorContains(t, err, "is newer than the supported version")
+	})
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{dsn: "", want: memoryDSN},
+		{dsn: "echo.db", want: "file:echo.db?_busy_timeout=5000"},
+		{dsn: "file:echo.db", want: "file:echo.db?_busy_timeout=5000"},
+		{dsn: "file:echo.db?mode=ro", want: "file:echo.db?mode=ro&_busy_timeout=5000"},
+		{dsn: "echo.db?_busy_timeout=100", want: "file:echo.db?_busy_timeout=100"},
+		{dsn: "file:echo.db?mode=ro&_timeout=100", want: "file:echo.db?mode=ro&_timeout=100"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dsn, func(t *testing.T) {
+			assert.Equal(t, test.want, parseDSN(test.dsn))
+		})
+	}
+}
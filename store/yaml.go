@@ -0,0 +1,29 @@
+package store
+
+import "fmt"
+
+// NormalizeYAML converts the maps of a document decoded from YAML to
+// map[string]any, like those decoded from JSON, since keys like status codes
+// are decoded as numbers.
+func NormalizeYAML(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = NormalizeYAML(child)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, child := range v {
+			m[fmt.Sprint(k)] = NormalizeYAML(child)
+		}
+		return m
+	case []any:
+		for i, child := range v {
+			v[i] = NormalizeYAML(child)
+		}
+		return v
+	default:
+		return v
+	}
+}